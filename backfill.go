@@ -2,22 +2,31 @@ package alchemywebhook
 
 import (
 	"context"
+
+	"github.com/dawitel/alchemy-webhook/backfill"
 )
 
 // Backfill handles historical transaction backfill
 type Backfill interface {
-	// Backfill performs backfill for the given addresses
-	Backfill(ctx context.Context, addresses []string) error
+	// Backfill starts a backfill for the given addresses and returns its job.
+	// While another backfill runs, it returns that job with
+	// backfill.ErrInProgress.
+	Backfill(ctx context.Context, addresses []string) (*backfill.Job, error)
 }
 
 // NoOpBackfill is a no-op implementation when backfill is disabled
-type NoOpBackfill struct{}
+type NoOpBackfill struct {
+	runner backfill.Runner
+}
 
 // NewNoOpBackfill creates a new no-op backfill
 func NewNoOpBackfill() *NoOpBackfill {
 	return &NoOpBackfill{}
 }
 
-func (b *NoOpBackfill) Backfill(ctx context.Context, addresses []string) error {
-	return nil
+// Backfill returns a job that has already succeeded without doing anything
+func (b *NoOpBackfill) Backfill(ctx context.Context, addresses []string) (*backfill.Job, error) {
+	return b.runner.Run(ctx, func(ctx context.Context, job *backfill.Job) error {
+		return nil
+	})
 }
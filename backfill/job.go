@@ -0,0 +1,234 @@
+// Package backfill tracks historical backfill runs as jobs with a status,
+// live progress, per-address errors and cancellation.
+package backfill
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"sync"
+	"time"
+)
+
+// ErrInProgress is returned when a backfill is started while another one is
+// still running
+var ErrInProgress = errors.New("backfill already in progress")
+
+// Status is the state of a job
+type Status string
+
+const (
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	StatusCancelled Status = "cancelled"
+)
+
+// Progress is a snapshot of the progress of a job
+type Progress struct {
+	AddressesTotal int
+	AddressesDone  int
+	Processed      int // Transactions processed
+	Skipped        int // Transactions skipped as already processed
+	Failed         int // Transactions that failed to process
+}
+
+// Job is a backfill run. Its getters are safe to call while it runs.
+type Job struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu         sync.Mutex
+	status     Status
+	progress   Progress
+	errors     map[string]error
+	err        error
+	startedAt  time.Time
+	finishedAt time.Time
+}
+
+// newJob creates a running job stopped by ctx or Cancel
+func newJob(ctx context.Context) *Job {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Job{
+		ctx:       ctx,
+		cancel:    cancel,
+		done:      make(chan struct{}),
+		status:    StatusRunning,
+		errors:    make(map[string]error),
+		startedAt: time.Now(),
+	}
+}
+
+// run runs fn and records how it ended
+func (j *Job) run(fn func(ctx context.Context, job *Job) error) {
+	defer close(j.done)
+	defer j.cancel()
+
+	err := fn(j.ctx, j)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.err = err
+	j.finishedAt = time.Now()
+	switch {
+	case err == nil:
+		j.status = StatusSucceeded
+	case j.ctx.Err() != nil:
+		j.status = StatusCancelled
+	default:
+		j.status = StatusFailed
+	}
+}
+
+// Status returns the state of the job
+func (j *Job) Status() Status {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.status
+}
+
+// Progress returns the progress so far
+func (j *Job) Progress() Progress {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.progress
+}
+
+// Errors returns the errors of the addresses that could not be fully
+// backfilled, by address
+func (j *Job) Errors() map[string]error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return maps.Clone(j.errors)
+}
+
+// Err returns the error the job ended with, nil while it runs
+func (j *Job) Err() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.err
+}
+
+// StartedAt returns when the job started
+func (j *Job) StartedAt() time.Time {
+	return j.startedAt
+}
+
+// FinishedAt returns when the job ended, zero while it runs
+func (j *Job) FinishedAt() time.Time {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.finishedAt
+}
+
+// Done returns a channel closed when the job ends
+func (j *Job) Done() <-chan struct{} {
+	return j.done
+}
+
+// Wait waits for the job to end and returns its error, or returns ctx's
+// error if ctx is done first
+func (j *Job) Wait(ctx context.Context) error {
+	select {
+	case <-j.done:
+		return j.Err()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Cancel stops the job. Work already done is kept.
+func (j *Job) Cancel() {
+	j.cancel()
+}
+
+// SetAddresses sets the number of addresses the job backfills
+func (j *Job) SetAddresses(total int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.progress.AddressesTotal = total
+}
+
+// CompleteAddresses marks n more addresses as done
+func (j *Job) CompleteAddresses(n int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.progress.AddressesDone += n
+}
+
+// Count adds to the transaction counts
+func (j *Job) Count(processed, skipped, failed int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.progress.Processed += processed
+	j.progress.Skipped += skipped
+	j.progress.Failed += failed
+}
+
+// AddressError records why an address could not be fully backfilled. Only
+// the first error of an address is kept.
+func (j *Job) AddressError(address string, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if _, ok := j.errors[address]; !ok {
+		j.errors[address] = err
+	}
+}
+
+// Runner runs at most one job at a time. The zero value is ready to use.
+type Runner struct {
+	mu      sync.Mutex
+	current *Job
+}
+
+// Start runs fn as a job in the background. While another job runs, it
+// returns that job with ErrInProgress, so callers can either give up or wait
+// for it.
+func (r *Runner) Start(ctx context.Context, fn func(ctx context.Context, job *Job) error) (*Job, error) {
+	job, err := r.begin(ctx)
+	if err != nil {
+		return job, err
+	}
+
+	go job.run(fn)
+	return job, nil
+}
+
+// Run runs fn as a job and waits for it to end. While another job runs, it
+// returns that job with ErrInProgress.
+func (r *Runner) Run(ctx context.Context, fn func(ctx context.Context, job *Job) error) (*Job, error) {
+	job, err := r.begin(ctx)
+	if err != nil {
+		return job, err
+	}
+
+	job.run(fn)
+	return job, job.Err()
+}
+
+// Current returns the last job started, nil if none
+func (r *Runner) Current() *Job {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.current
+}
+
+// begin creates the next job unless one is running
+func (r *Runner) begin(ctx context.Context) (*Job, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.current != nil {
+		select {
+		case <-r.current.done:
+		default:
+			return r.current, ErrInProgress
+		}
+	}
+
+	r.current = newJob(ctx)
+	return r.current, nil
+}
@@ -0,0 +1,109 @@
+package backfill
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRunnerRunsOneJobAtATime(t *testing.T) {
+	var runner Runner
+	release := make(chan struct{})
+
+	first, err := runner.Start(context.Background(), func(ctx context.Context, job *Job) error {
+		job.SetAddresses(2)
+		<-release
+		job.Count(3, 1, 0)
+		job.CompleteAddresses(2)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+
+	second, err := runner.Start(context.Background(), func(ctx context.Context, job *Job) error {
+		t.Error("second job ran while the first was running")
+		return nil
+	})
+	if !errors.Is(err, ErrInProgress) || second != first {
+		t.Fatalf("Start = %p, %v, want the running job %p with ErrInProgress", second, err, first)
+	}
+	if got := first.Status(); got != StatusRunning {
+		t.Errorf("status = %s, want %s", got, StatusRunning)
+	}
+
+	close(release)
+	if err := first.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait returned error: %v", err)
+	}
+
+	want := Progress{AddressesTotal: 2, AddressesDone: 2, Processed: 3, Skipped: 1}
+	if got := first.Progress(); got != want {
+		t.Errorf("progress = %+v, want %+v", got, want)
+	}
+	if got := first.Status(); got != StatusSucceeded {
+		t.Errorf("status = %s, want %s", got, StatusSucceeded)
+	}
+
+	if _, err := runner.Run(context.Background(), func(ctx context.Context, job *Job) error { return nil }); err != nil {
+		t.Errorf("Run after the first job ended returned error: %v", err)
+	}
+}
+
+func TestJobStatus(t *testing.T) {
+	errFetch := errors.New("fetch failed")
+
+	tests := []struct {
+		name       string
+		run        func(ctx context.Context, job *Job) error
+		wantStatus Status
+	}{
+		{
+			name:       "succeeded",
+			run:        func(ctx context.Context, job *Job) error { return nil },
+			wantStatus: StatusSucceeded,
+		},
+		{
+			name:       "failed",
+			run:        func(ctx context.Context, job *Job) error { return errFetch },
+			wantStatus: StatusFailed,
+		},
+		{
+			name: "cancelled",
+			run: func(ctx context.Context, job *Job) error {
+				job.Cancel()
+				<-ctx.Done()
+				return ctx.Err()
+			},
+			wantStatus: StatusCancelled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var runner Runner
+			job, _ := runner.Run(context.Background(), tt.run)
+
+			if got := job.Status(); got != tt.wantStatus {
+				t.Errorf("status = %s, want %s", got, tt.wantStatus)
+			}
+			if job.FinishedAt().IsZero() {
+				t.Error("finished job has no end time")
+			}
+		})
+	}
+}
+
+func TestJobKeepsFirstAddressError(t *testing.T) {
+	var runner Runner
+	job, _ := runner.Run(context.Background(), func(ctx context.Context, job *Job) error {
+		job.AddressError("a", errors.New("first"))
+		job.AddressError("a", errors.New("second"))
+		return nil
+	})
+
+	errs := job.Errors()
+	if len(errs) != 1 || errs["a"].Error() != "first" {
+		t.Errorf("errors = %v, want the first error of a", errs)
+	}
+}
@@ -0,0 +1,43 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// AsBatch returns c as a BatchCache. Caches without native batching are
+// wrapped in an adapter that issues one call per key.
+func AsBatch(c Cache) BatchCache {
+	if bc, ok := c.(BatchCache); ok {
+		return bc
+	}
+	return &batchAdapter{Cache: c}
+}
+
+// batchAdapter implements BatchCache on top of single-key calls
+type batchAdapter struct {
+	Cache
+}
+
+// IsProcessedBatch checks each key in turn
+func (a *batchAdapter) IsProcessedBatch(ctx context.Context, txHashes []string) ([]bool, error) {
+	results := make([]bool, len(txHashes))
+	for i, txHash := range txHashes {
+		processed, err := a.IsProcessed(ctx, txHash)
+		if err != nil {
+			return nil, err
+		}
+		results[i] = processed
+	}
+	return results, nil
+}
+
+// MarkProcessedBatch marks each key in turn
+func (a *batchAdapter) MarkProcessedBatch(ctx context.Context, txHashes []string, ttl time.Duration) error {
+	for _, txHash := range txHashes {
+		if err := a.MarkProcessed(ctx, txHash, ttl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestAsBatch(t *testing.T) {
+	ctx := context.Background()
+
+	memory := NewMemoryCache(100, time.Minute, false)
+	defer memory.Close()
+	if AsBatch(memory) != BatchCache(memory) {
+		t.Error("AsBatch wrapped a cache that batches natively")
+	}
+
+	remote := newFakeRemote()
+	adapter := AsBatch(remote)
+	if _, ok := adapter.(*batchAdapter); !ok {
+		t.Fatalf("AsBatch(%T) = %T, want the adapter", remote, adapter)
+	}
+
+	remote.MarkProcessed(ctx, "b", time.Hour)
+	got, err := adapter.IsProcessedBatch(ctx, []string{"a", "b", "c"})
+	if err != nil {
+		t.Fatalf("IsProcessedBatch returned error: %v", err)
+	}
+	if want := []bool{false, true, false}; !slices.Equal(got, want) {
+		t.Errorf("IsProcessedBatch = %v, want %v", got, want)
+	}
+
+	if err := adapter.MarkProcessedBatch(ctx, []string{"a", "c"}, time.Hour); err != nil {
+		t.Fatalf("MarkProcessedBatch returned error: %v", err)
+	}
+	if !remote.has("a") || !remote.has("c") {
+		t.Error("MarkProcessedBatch did not mark every key")
+	}
+
+	remote.err = errors.New("remote down")
+	if _, err := adapter.IsProcessedBatch(ctx, []string{"a"}); err == nil {
+		t.Error("IsProcessedBatch returned no error while the cache fails")
+	}
+	if err := adapter.MarkProcessedBatch(ctx, []string{"d"}, time.Hour); err == nil {
+		t.Error("MarkProcessedBatch returned no error while the cache fails")
+	}
+}
+
+func TestBatchCaches(t *testing.T) {
+	tests := []struct {
+		name     string
+		cache    func(t *testing.T) BatchCache
+		stateful bool
+	}{
+		{"memory", func(t *testing.T) BatchCache {
+			c := NewMemoryCache(100, time.Minute, true)
+			t.Cleanup(func() { c.Close() })
+			return c
+		}, true},
+		{"tiered", func(t *testing.T) BatchCache { return newTestTieredCache(t, newFakeRemote(), time.Minute) }, true},
+		{"noop", func(t *testing.T) BatchCache { return NewNoOpCache() }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			c := tt.cache(t)
+			keys := []string{"a", "b", "c", "d"}
+
+			if err := c.MarkProcessedBatch(ctx, []string{"d", "b"}, time.Hour); err != nil {
+				t.Fatalf("MarkProcessedBatch returned error: %v", err)
+			}
+			got, err := c.IsProcessedBatch(ctx, keys)
+			if err != nil {
+				t.Fatalf("IsProcessedBatch returned error: %v", err)
+			}
+			want := []bool{false, tt.stateful, false, tt.stateful}
+			if !slices.Equal(got, want) {
+				t.Errorf("IsProcessedBatch(%v) = %v, want %v", keys, got, want)
+			}
+
+			if got, _ := c.IsProcessedBatch(ctx, nil); len(got) != 0 {
+				t.Errorf("IsProcessedBatch(nil) = %v, want no results", got)
+			}
+		})
+	}
+}
+
+func TestTieredCacheBatchMixesTiers(t *testing.T) {
+	ctx := context.Background()
+	remote := newFakeRemote()
+	c := newTestTieredCache(t, remote, time.Minute)
+
+	c.MarkProcessed(ctx, "local", time.Hour)
+	remote.MarkProcessed(ctx, "remote", time.Hour)
+
+	got, err := c.IsProcessedBatch(ctx, []string{"missing", "local", "remote"})
+	if err != nil {
+		t.Fatalf("IsProcessedBatch returned error: %v", err)
+	}
+	if want := []bool{false, true, true}; !slices.Equal(got, want) {
+		t.Errorf("IsProcessedBatch = %v, want %v", got, want)
+	}
+	if remote.readCount() != 2 {
+		t.Errorf("remote tier read %d keys, want only the 2 not held locally", remote.readCount())
+	}
+
+	// The remote hit is now local and the miss negative, so nothing is read
+	got, _ = c.IsProcessedBatch(ctx, []string{"remote", "missing"})
+	if want := []bool{true, false}; !slices.Equal(got, want) {
+		t.Errorf("second IsProcessedBatch = %v, want %v", got, want)
+	}
+	if remote.readCount() != 2 {
+		t.Errorf("remote tier read %d keys after the second batch, want 2", remote.readCount())
+	}
+}
@@ -1,7 +1,6 @@
 package cache
 
 import (
-	"crypto/tls"
 	"fmt"
 	"time"
 )
@@ -11,9 +10,10 @@ const defaultCleanupInterval = 1 * time.Hour
 // CacheConfig represents the cache configuration
 type CacheConfig struct {
 	Enabled bool
-	Type    string // "redis" or "memory"
+	Type    string // "redis", "memory" or "tiered"
 	Redis   RedisConfig
 	Memory  MemoryConfig
+	Tiered  TieredConfig
 }
 
 // MemoryConfig represents memory cache configuration
@@ -42,38 +42,19 @@ func NewCache(cfg CacheConfig) (Cache, error) {
 		), nil
 
 	case "redis":
-		var tlsConfig *tls.Config
-		if cfg.Redis.EnableTLS {
-			if cfg.Redis.TLSConfig != nil {
-				if tc, ok := cfg.Redis.TLSConfig.(*tls.Config); ok {
-					tlsConfig = tc
-				} else {
-					tlsConfig = &tls.Config{
-						InsecureSkipVerify: cfg.Redis.TLSSkipVerify,
-					}
-				}
-			} else {
-				tlsConfig = &tls.Config{
-					InsecureSkipVerify: cfg.Redis.TLSSkipVerify,
-				}
-			}
-		}
+		return NewRedisCache(cfg.Redis)
 
-		redisConfig := RedisConfig{
-			Address:       cfg.Redis.Address,
-			Password:      cfg.Redis.Password,
-			DB:            cfg.Redis.DB,
-			PoolSize:      cfg.Redis.PoolSize,
-			MinIdleConns:  cfg.Redis.MinIdleConns,
-			DialTimeout:   cfg.Redis.DialTimeout,
-			ReadTimeout:   cfg.Redis.ReadTimeout,
-			WriteTimeout:  cfg.Redis.WriteTimeout,
-			EnableTLS:     cfg.Redis.EnableTLS,
-			TLSSkipVerify: cfg.Redis.TLSSkipVerify,
-			TLSConfig:     tlsConfig,
+	case "tiered":
+		remote, err := NewRedisCache(cfg.Redis)
+		if err != nil {
+			return nil, err
 		}
-
-		return NewRedisCache(redisConfig)
+		local := NewMemoryCache(
+			cfg.Memory.MaxSize,
+			cfg.Memory.CleanupInterval,
+			cfg.Memory.EnableLRU,
+		)
+		return NewTieredCache(local, remote, cfg.Tiered), nil
 
 	default:
 		return nil, fmt.Errorf("unknown cache type: %s", cfg.Type)
@@ -2,9 +2,13 @@ package cache
 
 import (
 	"context"
+	"errors"
 	"time"
 )
 
+// ErrNotSupported is returned when a cache does not support an optional operation
+var ErrNotSupported = errors.New("operation not supported by cache")
+
 // Cache defines the interface for transaction deduplication cache
 type Cache interface {
 	// IsProcessed checks if a transaction has been processed
@@ -17,3 +21,47 @@ type Cache interface {
 	Close() error
 }
 
+// BatchCache is implemented by caches that can check or mark many keys in one round trip
+type BatchCache interface {
+	Cache
+
+	// IsProcessedBatch reports, in key order, whether each transaction has been processed
+	IsProcessedBatch(ctx context.Context, txHashes []string) ([]bool, error)
+
+	// MarkProcessedBatch marks all transactions as processed
+	MarkProcessedBatch(ctx context.Context, txHashes []string, ttl time.Duration) error
+}
+
+// StatsProvider is implemented by caches that report usage statistics
+type StatsProvider interface {
+	// Stats returns a snapshot of the cache statistics
+	Stats(ctx context.Context) (Stats, error)
+}
+
+// Inspector is implemented by caches that allow single keys to be inspected
+type Inspector interface {
+	// Lookup returns the state of a single dedupe key
+	Lookup(ctx context.Context, key string) (Entry, error)
+
+	// Delete removes a single dedupe key so the transaction is processed again
+	Delete(ctx context.Context, key string) error
+}
+
+// Stats represents cache usage statistics
+type Stats struct {
+	Type      string
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64 // Entries removed to make room for new ones
+	Expired   uint64 // Entries removed because their TTL passed
+	Errors    uint64 // Backend errors
+	Size      int64  // Number of entries, -1 if unknown
+	Tiers     []Stats
+}
+
+// Entry represents the state of a single dedupe key
+type Entry struct {
+	Key    string
+	Exists bool
+	TTL    time.Duration // Remaining time to live, zero if the key has no expiry
+}
@@ -1,31 +1,81 @@
 package cache
 
 import (
+	"container/list"
 	"context"
+	"hash/fnv"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
-// MemoryCache is an in-memory cache implementation
+const (
+	// maxMemoryShards is the upper bound on the number of lock shards
+	maxMemoryShards = 32
+
+	// minEntriesPerShard keeps small caches on few shards so capacity stays close to maxSize
+	minEntriesPerShard = 1024
+)
+
+// memoryEntry is a single cache entry stored in a shard's order list
+type memoryEntry struct {
+	key       string
+	expiresAt time.Time
+}
+
+// memoryShard holds a subset of the cache entries behind its own lock.
+// The order list keeps the most recently used (LRU) or most recently
+// inserted (FIFO) entry at the front.
+type memoryShard struct {
+	mu      sync.RWMutex
+	entries map[string]*list.Element
+	order   *list.List
+	maxSize int
+}
+
+// MemoryCache is an in-memory cache implementation.
+// Entries are spread over lock shards; each shard evicts in O(1) from the
+// back of its order list once it reaches its share of maxSize.
 type MemoryCache struct {
-	mu       sync.RWMutex
-	entries  map[string]time.Time
-	maxSize  int
-	cleanup  *time.Ticker
-	stop     chan struct{}
+	shards    []*memoryShard
 	enableLRU bool
-	accessOrder []string // For LRU eviction
+	cleanup   *time.Ticker
+	stop      chan struct{}
+	closeOnce sync.Once
+
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	evictions atomic.Uint64
+	expired   atomic.Uint64
 }
 
-// NewMemoryCache creates a new in-memory cache
+// NewMemoryCache creates a new in-memory cache.
+// A maxSize of zero or less leaves the cache unbounded. With enableLRU the
+// least recently used entry is evicted first, otherwise the oldest inserted.
 func NewMemoryCache(maxSize int, cleanupInterval time.Duration, enableLRU bool) *MemoryCache {
+	if cleanupInterval <= 0 {
+		cleanupInterval = defaultCleanupInterval
+	}
+
+	shardCount := memoryShardCount(maxSize)
+	shards := make([]*memoryShard, shardCount)
+	for i := range shards {
+		shardSize := 0
+		if maxSize > 0 {
+			shardSize = (maxSize + shardCount - 1) / shardCount
+		}
+		shards[i] = &memoryShard{
+			entries: make(map[string]*list.Element),
+			order:   list.New(),
+			maxSize: shardSize,
+		}
+	}
+
 	cache := &MemoryCache{
-		entries:    make(map[string]time.Time),
-		maxSize:    maxSize,
-		cleanup:    time.NewTicker(cleanupInterval),
-		stop:       make(chan struct{}),
-		enableLRU:  enableLRU,
-		accessOrder: make([]string, 0, maxSize),
+		shards:    shards,
+		enableLRU: enableLRU,
+		cleanup:   time.NewTicker(cleanupInterval),
+		stop:      make(chan struct{}),
 	}
 
 	go cache.cleanupExpired()
@@ -33,89 +83,185 @@ func NewMemoryCache(maxSize int, cleanupInterval time.Duration, enableLRU bool)
 	return cache
 }
 
-// IsProcessed checks if a transaction has been processed
+// memoryShardCount picks a power of two shard count suited to maxSize
+func memoryShardCount(maxSize int) int {
+	if maxSize <= 0 {
+		return maxMemoryShards
+	}
+	count := 1
+	for count < maxMemoryShards && maxSize/(count*2) >= minEntriesPerShard {
+		count *= 2
+	}
+	return count
+}
+
+// shardFor returns the shard responsible for a key
+func (c *MemoryCache) shardFor(key string) *memoryShard {
+	if len(c.shards) == 1 {
+		return c.shards[0]
+	}
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return c.shards[h.Sum32()&uint32(len(c.shards)-1)]
+}
+
+// IsProcessed checks if a transaction has been processed.
+// Without LRU a hit only takes the shard read lock; an expired entry is
+// removed under the write lock after re-checking it.
 func (c *MemoryCache) IsProcessed(ctx context.Context, txHash string) (bool, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+	shard := c.shardFor(txHash)
+	now := time.Now()
+
+	if !c.enableLRU {
+		shard.mu.RLock()
+		elem, exists := shard.entries[txHash]
+		live := exists && !now.After(elem.Value.(*memoryEntry).expiresAt)
+		shard.mu.RUnlock()
+
+		if live {
+			c.hits.Add(1)
+			return true, nil
+		}
+		if !exists {
+			c.misses.Add(1)
+			return false, nil
+		}
+	}
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
 
-	expiresAt, exists := c.entries[txHash]
+	elem, exists := shard.entries[txHash]
 	if !exists {
+		c.misses.Add(1)
 		return false, nil
 	}
 
-	if time.Now().After(expiresAt) {
-		c.mu.RUnlock()
-		c.mu.Lock()
-		delete(c.entries, txHash)
-		if c.enableLRU {
-			c.removeFromAccessOrder(txHash)
-		}
-		c.mu.Unlock()
-		c.mu.RLock()
+	if now.After(elem.Value.(*memoryEntry).expiresAt) {
+		shard.remove(elem)
+		c.expired.Add(1)
+		c.misses.Add(1)
 		return false, nil
 	}
 
 	if c.enableLRU {
-		c.mu.RUnlock()
-		c.mu.Lock()
-		c.updateAccessOrder(txHash)
-		c.mu.Unlock()
-		c.mu.RLock()
+		shard.order.MoveToFront(elem)
 	}
 
+	c.hits.Add(1)
 	return true, nil
 }
 
 // MarkProcessed marks a transaction as processed
 func (c *MemoryCache) MarkProcessed(ctx context.Context, txHash string, ttl time.Duration) error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	shard := c.shardFor(txHash)
+	expiresAt := time.Now().Add(ttl)
 
-	if len(c.entries) >= c.maxSize {
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if elem, exists := shard.entries[txHash]; exists {
+		elem.Value.(*memoryEntry).expiresAt = expiresAt
 		if c.enableLRU {
-			if len(c.accessOrder) > 0 {
-				oldest := c.accessOrder[0]
-				delete(c.entries, oldest)
-				c.accessOrder = c.accessOrder[1:]
-			}
-		} else {
-			now := time.Now()
-			evicted := false
-			for key, expiresAt := range c.entries {
-				if now.After(expiresAt) {
-					delete(c.entries, key)
-					evicted = true
-					break
-				}
-			}
-			if !evicted && len(c.entries) > 0 {
-				for key := range c.entries {
-					delete(c.entries, key)
-					break
-				}
-			}
+			shard.order.MoveToFront(elem)
 		}
+		return nil
 	}
 
-	expiresAt := time.Now().Add(ttl)
-	c.entries[txHash] = expiresAt
+	if shard.maxSize > 0 && len(shard.entries) >= shard.maxSize {
+		if oldest := shard.order.Back(); oldest != nil {
+			shard.remove(oldest)
+			c.evictions.Add(1)
+		}
+	}
 
-	if c.enableLRU {
-		c.updateAccessOrder(txHash)
+	elem := shard.order.PushFront(&memoryEntry{key: txHash, expiresAt: expiresAt})
+	shard.entries[txHash] = elem
+
+	return nil
+}
+
+// IsProcessedBatch checks several transactions at once
+func (c *MemoryCache) IsProcessedBatch(ctx context.Context, txHashes []string) ([]bool, error) {
+	results := make([]bool, len(txHashes))
+	for i, txHash := range txHashes {
+		results[i], _ = c.IsProcessed(ctx, txHash)
+	}
+	return results, nil
+}
+
+// MarkProcessedBatch marks several transactions as processed
+func (c *MemoryCache) MarkProcessedBatch(ctx context.Context, txHashes []string, ttl time.Duration) error {
+	for _, txHash := range txHashes {
+		c.MarkProcessed(ctx, txHash, ttl)
+	}
+	return nil
+}
+
+// Lookup returns the state of a single dedupe key without updating LRU order
+func (c *MemoryCache) Lookup(ctx context.Context, key string) (Entry, error) {
+	shard := c.shardFor(key)
+
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+
+	entry := Entry{Key: key}
+	if elem, exists := shard.entries[key]; exists {
+		if remaining := time.Until(elem.Value.(*memoryEntry).expiresAt); remaining > 0 {
+			entry.Exists = true
+			entry.TTL = remaining
+		}
+	}
+
+	return entry, nil
+}
+
+// Delete removes a single dedupe key
+func (c *MemoryCache) Delete(ctx context.Context, key string) error {
+	shard := c.shardFor(key)
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if elem, exists := shard.entries[key]; exists {
+		shard.remove(elem)
 	}
 
 	return nil
 }
 
+// Stats returns a snapshot of the cache statistics
+func (c *MemoryCache) Stats(ctx context.Context) (Stats, error) {
+	var size int64
+	for _, shard := range c.shards {
+		shard.mu.RLock()
+		size += int64(len(shard.entries))
+		shard.mu.RUnlock()
+	}
+
+	return Stats{
+		Type:      "memory",
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Evictions: c.evictions.Load(),
+		Expired:   c.expired.Load(),
+		Size:      size,
+	}, nil
+}
+
 // Close closes the cache and releases resources
 func (c *MemoryCache) Close() error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	c.closeOnce.Do(func() {
+		c.cleanup.Stop()
+		close(c.stop)
 
-	c.cleanup.Stop()
-	close(c.stop)
-	c.entries = nil
-	c.accessOrder = nil
+		for _, shard := range c.shards {
+			shard.mu.Lock()
+			shard.entries = make(map[string]*list.Element)
+			shard.order.Init()
+			shard.mu.Unlock()
+		}
+	})
 
 	return nil
 }
@@ -125,36 +271,27 @@ func (c *MemoryCache) cleanupExpired() {
 	for {
 		select {
 		case <-c.cleanup.C:
-			c.mu.Lock()
 			now := time.Now()
-			for key, expiresAt := range c.entries {
-				if now.After(expiresAt) {
-					delete(c.entries, key)
-					if c.enableLRU {
-						c.removeFromAccessOrder(key)
+			for _, shard := range c.shards {
+				shard.mu.Lock()
+				for elem := shard.order.Back(); elem != nil; {
+					prev := elem.Prev()
+					if now.After(elem.Value.(*memoryEntry).expiresAt) {
+						shard.remove(elem)
+						c.expired.Add(1)
 					}
+					elem = prev
 				}
+				shard.mu.Unlock()
 			}
-			c.mu.Unlock()
 		case <-c.stop:
 			return
 		}
 	}
 }
 
-// updateAccessOrder updates the access order for LRU
-func (c *MemoryCache) updateAccessOrder(txHash string) {
-	c.removeFromAccessOrder(txHash)
-	c.accessOrder = append(c.accessOrder, txHash)
+// remove deletes an element from the shard; the caller must hold the shard lock
+func (s *memoryShard) remove(elem *list.Element) {
+	entry := s.order.Remove(elem).(*memoryEntry)
+	delete(s.entries, entry.key)
 }
-
-// removeFromAccessOrder removes an entry from access order
-func (c *MemoryCache) removeFromAccessOrder(txHash string) {
-	for i, key := range c.accessOrder {
-		if key == txHash {
-			c.accessOrder = append(c.accessOrder[:i], c.accessOrder[i+1:]...)
-			return
-		}
-	}
-}
-
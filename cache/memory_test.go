@@ -0,0 +1,222 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+)
+
+const benchmarkCacheSize = 100000
+
+func newBenchmarkCache(b *testing.B, enableLRU bool) (*MemoryCache, []string) {
+	b.Helper()
+
+	c := NewMemoryCache(benchmarkCacheSize, time.Hour, enableLRU)
+	b.Cleanup(func() { c.Close() })
+
+	keys := make([]string, benchmarkCacheSize)
+	ctx := context.Background()
+	for i := range keys {
+		keys[i] = "0x" + strconv.FormatInt(int64(i), 16)
+		c.MarkProcessed(ctx, keys[i], time.Hour)
+	}
+
+	return c, keys
+}
+
+func benchmarkIsProcessedParallel(b *testing.B, enableLRU bool) {
+	c, keys := newBenchmarkCache(b, enableLRU)
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			c.IsProcessed(ctx, keys[i%len(keys)])
+			i++
+		}
+	})
+}
+
+func BenchmarkMemoryCacheIsProcessedParallel(b *testing.B) {
+	benchmarkIsProcessedParallel(b, false)
+}
+
+func BenchmarkMemoryCacheIsProcessedParallelLRU(b *testing.B) {
+	benchmarkIsProcessedParallel(b, true)
+}
+
+func BenchmarkMemoryCacheIsProcessedMiss(b *testing.B) {
+	c, _ := newBenchmarkCache(b, true)
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.IsProcessed(ctx, "missing")
+	}
+}
+
+func benchmarkMarkProcessedEvicting(b *testing.B, enableLRU bool) {
+	c, _ := newBenchmarkCache(b, enableLRU)
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			c.MarkProcessed(ctx, "new-"+strconv.Itoa(i), time.Hour)
+			i++
+		}
+	})
+}
+
+func BenchmarkMemoryCacheMarkProcessedEvicting(b *testing.B) {
+	benchmarkMarkProcessedEvicting(b, false)
+}
+
+func BenchmarkMemoryCacheMarkProcessedEvictingLRU(b *testing.B) {
+	benchmarkMarkProcessedEvicting(b, true)
+}
+
+func TestMemoryCacheStatsLookupDelete(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(100, time.Minute, false)
+	defer c.Close()
+
+	c.MarkProcessed(ctx, "live", time.Hour)
+	c.MarkProcessed(ctx, "short", 20*time.Millisecond)
+
+	entry, _ := c.Lookup(ctx, "live")
+	if !entry.Exists || entry.TTL <= 59*time.Minute || entry.TTL > time.Hour {
+		t.Errorf("Lookup(live) = %+v, want an entry with about an hour left", entry)
+	}
+	if entry, _ := c.Lookup(ctx, "missing"); entry.Exists || entry.Key != "missing" {
+		t.Errorf("Lookup(missing) = %+v, want a missing entry", entry)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if entry, _ := c.Lookup(ctx, "short"); entry.Exists {
+		t.Errorf("Lookup(short) = %+v after its TTL, want a missing entry", entry)
+	}
+
+	c.IsProcessed(ctx, "live")    // hit
+	c.IsProcessed(ctx, "short")   // expired on read
+	c.IsProcessed(ctx, "missing") // miss
+
+	if err := c.Delete(ctx, "live"); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	if processed, _ := c.IsProcessed(ctx, "live"); processed { // miss
+		t.Error("deleted key is still processed")
+	}
+
+	stats, err := c.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Stats returned error: %v", err)
+	}
+	want := Stats{Type: "memory", Hits: 1, Misses: 3, Expired: 1, Size: 0}
+	if stats.Type != want.Type || stats.Hits != want.Hits || stats.Misses != want.Misses ||
+		stats.Expired != want.Expired || stats.Size != want.Size {
+		t.Errorf("Stats() = %+v, want %+v", stats, want)
+	}
+}
+
+func TestMemoryCacheEvictionOrder(t *testing.T) {
+	tests := []struct {
+		name      string
+		enableLRU bool
+		evicted   string
+	}{
+		{"fifo evicts the oldest inserted", false, "a"},
+		{"lru evicts the least recently used", true, "b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			c := NewMemoryCache(3, time.Minute, tt.enableLRU)
+			defer c.Close()
+
+			for _, key := range []string{"a", "b", "c"} {
+				c.MarkProcessed(ctx, key, time.Hour)
+			}
+			c.IsProcessed(ctx, "a")
+			c.MarkProcessed(ctx, "d", time.Hour)
+
+			for _, key := range []string{"a", "b", "c", "d"} {
+				entry, _ := c.Lookup(ctx, key)
+				if entry.Exists == (key == tt.evicted) {
+					t.Errorf("key %s exists = %v, want evicted %s", key, entry.Exists, tt.evicted)
+				}
+			}
+			if stats, _ := c.Stats(ctx); stats.Evictions != 1 || stats.Size != 3 {
+				t.Errorf("Stats() = %+v, want 1 eviction and 3 entries", stats)
+			}
+		})
+	}
+}
+
+func TestMemoryCacheExpiresOnRead(t *testing.T) {
+	for _, enableLRU := range []bool{false, true} {
+		ctx := context.Background()
+		c := NewMemoryCache(100, time.Hour, enableLRU)
+
+		c.MarkProcessed(ctx, "key", 10*time.Millisecond)
+		if processed, _ := c.IsProcessed(ctx, "key"); !processed {
+			t.Errorf("lru=%v: key is not processed before its TTL", enableLRU)
+		}
+		time.Sleep(20 * time.Millisecond)
+		if processed, _ := c.IsProcessed(ctx, "key"); processed {
+			t.Errorf("lru=%v: key is processed after its TTL", enableLRU)
+		}
+
+		stats, _ := c.Stats(ctx)
+		if stats.Expired != 1 || stats.Size != 0 {
+			t.Errorf("lru=%v: Stats() = %+v, want the entry removed as expired", enableLRU, stats)
+		}
+
+		// Marking again revives the key
+		c.MarkProcessed(ctx, "key", time.Hour)
+		if processed, _ := c.IsProcessed(ctx, "key"); !processed {
+			t.Errorf("lru=%v: re-marked key is not processed", enableLRU)
+		}
+		c.Close()
+	}
+}
+
+func TestMemoryCacheShardCapacity(t *testing.T) {
+	for _, tt := range []struct{ maxSize, shards int }{
+		{0, maxMemoryShards},
+		{100, 1},
+		{4096, 4},
+		{1 << 20, maxMemoryShards},
+	} {
+		if got := memoryShardCount(tt.maxSize); got != tt.shards {
+			t.Errorf("memoryShardCount(%d) = %d, want %d", tt.maxSize, got, tt.shards)
+		}
+	}
+
+	ctx := context.Background()
+	c := NewMemoryCache(4096, time.Minute, false)
+	defer c.Close()
+
+	const inserted = 10000
+	for i := 0; i < inserted; i++ {
+		c.MarkProcessed(ctx, "0x"+strconv.Itoa(i), time.Hour)
+	}
+
+	for i, shard := range c.shards {
+		if shard.maxSize != 1024 || len(shard.entries) > shard.maxSize || shard.order.Len() != len(shard.entries) {
+			t.Errorf("shard %d holds %d entries (%d in order) with capacity %d, want at most 1024",
+				i, len(shard.entries), shard.order.Len(), shard.maxSize)
+		}
+	}
+	stats, _ := c.Stats(ctx)
+	if stats.Size > 4096 || stats.Evictions != uint64(inserted-stats.Size) {
+		t.Errorf("Stats() = %+v, want at most 4096 entries and the rest evicted", stats)
+	}
+}
@@ -2,11 +2,14 @@ package cache
 
 import (
 	"context"
+	"sync/atomic"
 	"time"
 )
 
 // NoOpCache is a no-op cache implementation used when caching is disabled.
-type NoOpCache struct{}
+type NoOpCache struct {
+	misses atomic.Uint64
+}
 
 // NewNoOpCache creates a new no-op cache instance.
 func NewNoOpCache() *NoOpCache {
@@ -15,6 +18,7 @@ func NewNoOpCache() *NoOpCache {
 
 // IsProcessed returns false indicating the transaction has not been processed.
 func (c *NoOpCache) IsProcessed(ctx context.Context, txHash string) (bool, error) {
+	c.misses.Add(1)
 	return false, nil
 }
 
@@ -23,8 +27,36 @@ func (c *NoOpCache) MarkProcessed(ctx context.Context, txHash string, ttl time.D
 	return nil
 }
 
+// IsProcessedBatch reports every transaction as not processed.
+func (c *NoOpCache) IsProcessedBatch(ctx context.Context, txHashes []string) ([]bool, error) {
+	c.misses.Add(uint64(len(txHashes)))
+	return make([]bool, len(txHashes)), nil
+}
+
+// MarkProcessedBatch is a no-op that does not persist any state.
+func (c *NoOpCache) MarkProcessedBatch(ctx context.Context, txHashes []string, ttl time.Duration) error {
+	return nil
+}
+
+// Lookup always reports the key as absent.
+func (c *NoOpCache) Lookup(ctx context.Context, key string) (Entry, error) {
+	return Entry{Key: key}, nil
+}
+
+// Delete is a no-op since no keys are stored.
+func (c *NoOpCache) Delete(ctx context.Context, key string) error {
+	return nil
+}
+
+// Stats returns statistics for the no-op cache; every lookup is a miss.
+func (c *NoOpCache) Stats(ctx context.Context) (Stats, error) {
+	return Stats{
+		Type:   "noop",
+		Misses: c.misses.Load(),
+	}, nil
+}
+
 // Close is a no-op that does not release any resources.
 func (c *NoOpCache) Close() error {
 	return nil
 }
-
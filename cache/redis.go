@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-redis/redis/v8"
@@ -13,10 +14,29 @@ import (
 type RedisCache struct {
 	client *redis.Client
 	prefix string
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+	errors atomic.Uint64
 }
 
 // NewRedisCache creates a new Redis cache
 func NewRedisCache(config RedisConfig) (*RedisCache, error) {
+	client, err := NewRedisClient(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RedisCache{
+		client: client,
+		prefix: "alchemy_webhook:",
+	}, nil
+}
+
+// NewRedisClient creates a Redis client from the configuration, resolving
+// its TLS settings, and checks the connection. It is shared with other
+// Redis-backed stores such as backfill checkpoints.
+func NewRedisClient(config RedisConfig) (*redis.Client, error) {
 	opts := &redis.Options{
 		Addr:         config.Address,
 		Password:     config.Password,
@@ -44,13 +64,11 @@ func NewRedisCache(config RedisConfig) (*RedisCache, error) {
 	defer cancel()
 
 	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
 		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
 	}
 
-	return &RedisCache{
-		client: client,
-		prefix: "alchemy_webhook:",
-	}, nil
+	return client, nil
 }
 
 // IsProcessed checks if a transaction has been processed
@@ -58,9 +76,15 @@ func (c *RedisCache) IsProcessed(ctx context.Context, txHash string) (bool, erro
 	key := c.prefix + txHash
 	exists, err := c.client.Exists(ctx, key).Result()
 	if err != nil {
+		c.errors.Add(1)
 		return false, fmt.Errorf("failed to check Redis key: %w", err)
 	}
-	return exists > 0, nil
+	if exists > 0 {
+		c.hits.Add(1)
+		return true, nil
+	}
+	c.misses.Add(1)
+	return false, nil
 }
 
 // MarkProcessed marks a transaction as processed
@@ -68,11 +92,119 @@ func (c *RedisCache) MarkProcessed(ctx context.Context, txHash string, ttl time.
 	key := c.prefix + txHash
 	err := c.client.Set(ctx, key, "1", ttl).Err()
 	if err != nil {
+		c.errors.Add(1)
 		return fmt.Errorf("failed to set Redis key: %w", err)
 	}
 	return nil
 }
 
+// IsProcessedBatch checks several transactions with a single MGET
+func (c *RedisCache) IsProcessedBatch(ctx context.Context, txHashes []string) ([]bool, error) {
+	if len(txHashes) == 0 {
+		return nil, nil
+	}
+
+	keys := make([]string, len(txHashes))
+	for i, txHash := range txHashes {
+		keys[i] = c.prefix + txHash
+	}
+
+	values, err := c.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		c.errors.Add(1)
+		return nil, fmt.Errorf("failed to check Redis keys: %w", err)
+	}
+
+	results := make([]bool, len(txHashes))
+	for i, value := range values {
+		results[i] = value != nil
+		if results[i] {
+			c.hits.Add(1)
+		} else {
+			c.misses.Add(1)
+		}
+	}
+	return results, nil
+}
+
+// MarkProcessedBatch marks several transactions as processed in one pipeline
+func (c *RedisCache) MarkProcessedBatch(ctx context.Context, txHashes []string, ttl time.Duration) error {
+	if len(txHashes) == 0 {
+		return nil
+	}
+
+	_, err := c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, txHash := range txHashes {
+			pipe.Set(ctx, c.prefix+txHash, "1", ttl)
+		}
+		return nil
+	})
+	if err != nil {
+		c.errors.Add(1)
+		return fmt.Errorf("failed to set Redis keys: %w", err)
+	}
+	return nil
+}
+
+// Lookup returns the state of a single dedupe key
+func (c *RedisCache) Lookup(ctx context.Context, key string) (Entry, error) {
+	ttl, err := c.client.PTTL(ctx, c.prefix+key).Result()
+	if err != nil {
+		c.errors.Add(1)
+		return Entry{}, fmt.Errorf("failed to look up Redis key: %w", err)
+	}
+
+	entry := Entry{Key: key}
+	switch {
+	case ttl == -2:
+		// Key does not exist
+	case ttl == -1:
+		// Key exists without an expiry
+		entry.Exists = true
+	default:
+		entry.Exists = true
+		entry.TTL = ttl
+	}
+
+	return entry, nil
+}
+
+// Delete removes a single dedupe key
+func (c *RedisCache) Delete(ctx context.Context, key string) error {
+	if err := c.client.Del(ctx, c.prefix+key).Err(); err != nil {
+		c.errors.Add(1)
+		return fmt.Errorf("failed to delete Redis key: %w", err)
+	}
+	return nil
+}
+
+// Stats returns a snapshot of the cache statistics.
+// Size is counted with SCAN over the key prefix, so it is meant for
+// occasional introspection rather than frequent polling.
+func (c *RedisCache) Stats(ctx context.Context) (Stats, error) {
+	stats := Stats{
+		Type:   "redis",
+		Hits:   c.hits.Load(),
+		Misses: c.misses.Load(),
+		Size:   -1,
+	}
+
+	var size int64
+	iter := c.client.Scan(ctx, 0, c.prefix+"*", 1000).Iterator()
+	for iter.Next(ctx) {
+		size++
+	}
+	if err := iter.Err(); err != nil {
+		c.errors.Add(1)
+		stats.Errors = c.errors.Load()
+		return stats, fmt.Errorf("failed to count Redis keys: %w", err)
+	}
+
+	stats.Size = size
+	stats.Errors = c.errors.Load()
+	return stats, nil
+}
+
 // Close closes the cache and releases resources
 func (c *RedisCache) Close() error {
 	return c.client.Close()
@@ -0,0 +1,248 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultTieredLocalTTL    = 10 * time.Minute
+	defaultTieredNegativeTTL = 5 * time.Second
+	defaultNegativeCacheSize = 10000
+)
+
+// TieredConfig configures the two-tier cache
+type TieredConfig struct {
+	// LocalTTL caps how long an entry is held in the local tier, 10 minutes
+	// when zero
+	LocalTTL time.Duration
+	// NegativeTTL is how long a "not processed" answer from the remote tier
+	// is trusted, 5 seconds when zero. A negative value disables it.
+	NegativeTTL time.Duration
+}
+
+// TieredCache checks a bounded local MemoryCache before falling back to a
+// remote cache. Writes go through to both tiers. Negative results are held
+// only for NegativeTTL so marks made by other replicas become visible quickly.
+type TieredCache struct {
+	local       *MemoryCache
+	negative    *MemoryCache
+	remote      Cache
+	localTTL    time.Duration
+	negativeTTL time.Duration
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+	errors atomic.Uint64
+}
+
+// NewTieredCache creates a new two-tier cache
+func NewTieredCache(local *MemoryCache, remote Cache, config TieredConfig) *TieredCache {
+	localTTL := config.LocalTTL
+	if localTTL <= 0 {
+		localTTL = defaultTieredLocalTTL
+	}
+
+	negativeTTL := config.NegativeTTL
+	if negativeTTL == 0 {
+		negativeTTL = defaultTieredNegativeTTL
+	}
+	var negative *MemoryCache
+	if negativeTTL > 0 {
+		negative = NewMemoryCache(defaultNegativeCacheSize, negativeTTL, false)
+	}
+
+	return &TieredCache{
+		local:       local,
+		negative:    negative,
+		remote:      remote,
+		localTTL:    localTTL,
+		negativeTTL: negativeTTL,
+	}
+}
+
+// IsProcessed checks the local tier first and falls back to the remote tier
+func (c *TieredCache) IsProcessed(ctx context.Context, txHash string) (bool, error) {
+	if processed, _ := c.local.IsProcessed(ctx, txHash); processed {
+		c.hits.Add(1)
+		return true, nil
+	}
+
+	if c.negative != nil {
+		if known, _ := c.negative.IsProcessed(ctx, txHash); known {
+			c.misses.Add(1)
+			return false, nil
+		}
+	}
+
+	processed, err := c.remote.IsProcessed(ctx, txHash)
+	if err != nil {
+		c.errors.Add(1)
+		return false, err
+	}
+
+	if processed {
+		c.hits.Add(1)
+		c.local.MarkProcessed(ctx, txHash, c.localTTL)
+	} else {
+		c.misses.Add(1)
+		if c.negative != nil {
+			c.negative.MarkProcessed(ctx, txHash, c.negativeTTL)
+		}
+	}
+
+	return processed, nil
+}
+
+// MarkProcessed writes the mark to the remote tier and then to the local tier
+func (c *TieredCache) MarkProcessed(ctx context.Context, txHash string, ttl time.Duration) error {
+	if err := c.remote.MarkProcessed(ctx, txHash, ttl); err != nil {
+		c.errors.Add(1)
+		return err
+	}
+
+	localTTL := ttl
+	if localTTL <= 0 || localTTL > c.localTTL {
+		localTTL = c.localTTL
+	}
+	c.local.MarkProcessed(ctx, txHash, localTTL)
+	if c.negative != nil {
+		c.negative.Delete(ctx, txHash)
+	}
+
+	return nil
+}
+
+// IsProcessedBatch checks the local tier and sends the remaining keys to the remote tier in one batch
+func (c *TieredCache) IsProcessedBatch(ctx context.Context, txHashes []string) ([]bool, error) {
+	results := make([]bool, len(txHashes))
+	var pending []int
+	for i, txHash := range txHashes {
+		if processed, _ := c.local.IsProcessed(ctx, txHash); processed {
+			c.hits.Add(1)
+			results[i] = true
+			continue
+		}
+		if c.negative != nil {
+			if known, _ := c.negative.IsProcessed(ctx, txHash); known {
+				c.misses.Add(1)
+				continue
+			}
+		}
+		pending = append(pending, i)
+	}
+
+	if len(pending) == 0 {
+		return results, nil
+	}
+
+	remoteKeys := make([]string, len(pending))
+	for j, i := range pending {
+		remoteKeys[j] = txHashes[i]
+	}
+
+	remoteResults, err := AsBatch(c.remote).IsProcessedBatch(ctx, remoteKeys)
+	if err != nil {
+		c.errors.Add(1)
+		return nil, err
+	}
+
+	for j, i := range pending {
+		results[i] = remoteResults[j]
+		if remoteResults[j] {
+			c.hits.Add(1)
+			c.local.MarkProcessed(ctx, txHashes[i], c.localTTL)
+		} else {
+			c.misses.Add(1)
+			if c.negative != nil {
+				c.negative.MarkProcessed(ctx, txHashes[i], c.negativeTTL)
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// MarkProcessedBatch writes the marks to the remote tier in one batch and then to the local tier
+func (c *TieredCache) MarkProcessedBatch(ctx context.Context, txHashes []string, ttl time.Duration) error {
+	if err := AsBatch(c.remote).MarkProcessedBatch(ctx, txHashes, ttl); err != nil {
+		c.errors.Add(1)
+		return err
+	}
+
+	localTTL := ttl
+	if localTTL <= 0 || localTTL > c.localTTL {
+		localTTL = c.localTTL
+	}
+	for _, txHash := range txHashes {
+		c.local.MarkProcessed(ctx, txHash, localTTL)
+		if c.negative != nil {
+			c.negative.Delete(ctx, txHash)
+		}
+	}
+
+	return nil
+}
+
+// Lookup returns the state of a single dedupe key from the remote tier
+func (c *TieredCache) Lookup(ctx context.Context, key string) (Entry, error) {
+	if inspector, ok := c.remote.(Inspector); ok {
+		return inspector.Lookup(ctx, key)
+	}
+	return c.local.Lookup(ctx, key)
+}
+
+// Delete removes a single dedupe key from both tiers
+func (c *TieredCache) Delete(ctx context.Context, key string) error {
+	c.local.Delete(ctx, key)
+	if c.negative != nil {
+		c.negative.Delete(ctx, key)
+	}
+
+	inspector, ok := c.remote.(Inspector)
+	if !ok {
+		return ErrNotSupported
+	}
+	return inspector.Delete(ctx, key)
+}
+
+// Stats returns the combined statistics with per-tier details in Tiers
+func (c *TieredCache) Stats(ctx context.Context) (Stats, error) {
+	localStats, _ := c.local.Stats(ctx)
+
+	stats := Stats{
+		Type:      "tiered",
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Evictions: localStats.Evictions,
+		Expired:   localStats.Expired,
+		Errors:    c.errors.Load(),
+		Size:      -1,
+		Tiers:     []Stats{localStats},
+	}
+
+	if provider, ok := c.remote.(StatsProvider); ok {
+		remoteStats, err := provider.Stats(ctx)
+		stats.Tiers = append(stats.Tiers, remoteStats)
+		stats.Size = remoteStats.Size
+		if err != nil {
+			return stats, err
+		}
+	}
+
+	return stats, nil
+}
+
+// Close closes both tiers and releases resources
+func (c *TieredCache) Close() error {
+	c.local.Close()
+	if c.negative != nil {
+		c.negative.Close()
+	}
+	if err := c.remote.Close(); err != nil {
+		return fmt.Errorf("failed to close remote cache: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,271 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRemote is a remote tier that counts IsProcessed calls. It implements
+// neither BatchCache nor Inspector.
+type fakeRemote struct {
+	mu    sync.Mutex
+	keys  map[string]struct{}
+	reads int
+	err   error
+}
+
+func newFakeRemote() *fakeRemote {
+	return &fakeRemote{keys: make(map[string]struct{})}
+}
+
+func (r *fakeRemote) IsProcessed(ctx context.Context, txHash string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reads++
+	if r.err != nil {
+		return false, r.err
+	}
+	_, ok := r.keys[txHash]
+	return ok, nil
+}
+
+func (r *fakeRemote) MarkProcessed(ctx context.Context, txHash string, ttl time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+	r.keys[txHash] = struct{}{}
+	return nil
+}
+
+func (r *fakeRemote) Close() error { return nil }
+
+func (r *fakeRemote) readCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.reads
+}
+
+func (r *fakeRemote) has(key string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.keys[key]
+	return ok
+}
+
+// inspectableRemote is a fakeRemote that also implements Inspector
+type inspectableRemote struct {
+	*fakeRemote
+}
+
+func (r inspectableRemote) Lookup(ctx context.Context, key string) (Entry, error) {
+	return Entry{Key: key, Exists: r.has(key)}, nil
+}
+
+func (r inspectableRemote) Delete(ctx context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.keys, key)
+	return nil
+}
+
+// newTestTieredCache returns a tiered cache over remote with a small local tier
+func newTestTieredCache(t *testing.T, remote Cache, negativeTTL time.Duration) *TieredCache {
+	t.Helper()
+	c := NewTieredCache(NewMemoryCache(100, time.Minute, true), remote, TieredConfig{NegativeTTL: negativeTTL})
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestTieredCacheLocalHits(t *testing.T) {
+	ctx := context.Background()
+	remote := newFakeRemote()
+	c := newTestTieredCache(t, remote, -1)
+
+	if err := c.MarkProcessed(ctx, "marked", time.Hour); err != nil {
+		t.Fatalf("MarkProcessed returned error: %v", err)
+	}
+	if processed, _ := c.IsProcessed(ctx, "marked"); !processed {
+		t.Error("key marked through the cache is not processed")
+	}
+	if remote.readCount() != 0 {
+		t.Errorf("local hit read the remote tier %d times", remote.readCount())
+	}
+
+	// A key marked by another replica is read once and then held locally
+	remote.MarkProcessed(ctx, "replica", time.Hour)
+	for i := 0; i < 2; i++ {
+		if processed, _ := c.IsProcessed(ctx, "replica"); !processed {
+			t.Fatalf("read %d: key marked on the remote tier is not processed", i)
+		}
+	}
+	if remote.readCount() != 1 {
+		t.Errorf("remote tier read %d times, want 1", remote.readCount())
+	}
+}
+
+func TestTieredCacheNegativeTTL(t *testing.T) {
+	tests := []struct {
+		name        string
+		negativeTTL time.Duration
+		wantTTL     time.Duration
+		wantReads   int
+	}{
+		{"default", 0, defaultTieredNegativeTTL, 1},
+		{"explicit", time.Minute, time.Minute, 1},
+		{"disabled", -1, -1, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remote := newFakeRemote()
+			c := newTestTieredCache(t, remote, tt.negativeTTL)
+			if c.negativeTTL != tt.wantTTL {
+				t.Errorf("negative TTL = %s, want %s", c.negativeTTL, tt.wantTTL)
+			}
+
+			for i := 0; i < 2; i++ {
+				c.IsProcessed(context.Background(), "key")
+			}
+			if remote.readCount() != tt.wantReads {
+				t.Errorf("remote tier read %d times, want %d", remote.readCount(), tt.wantReads)
+			}
+		})
+	}
+}
+
+func TestTieredCacheNegativeExpiry(t *testing.T) {
+	ctx := context.Background()
+	remote := newFakeRemote()
+	c := newTestTieredCache(t, remote, 50*time.Millisecond)
+
+	for i := 0; i < 2; i++ {
+		if processed, _ := c.IsProcessed(ctx, "key"); processed {
+			t.Fatalf("read %d: unknown key is processed", i)
+		}
+	}
+	if remote.readCount() != 1 {
+		t.Fatalf("negative result read the remote tier %d times, want 1", remote.readCount())
+	}
+
+	// Marked by another replica: hidden until the negative entry expires
+	remote.MarkProcessed(ctx, "key", time.Hour)
+	if processed, _ := c.IsProcessed(ctx, "key"); processed {
+		t.Error("negative entry was not used before it expired")
+	}
+	time.Sleep(60 * time.Millisecond)
+	if processed, _ := c.IsProcessed(ctx, "key"); !processed {
+		t.Error("remote mark is not visible after the negative entry expired")
+	}
+	if remote.readCount() != 2 {
+		t.Errorf("remote tier read %d times, want 2", remote.readCount())
+	}
+
+	// Marking through the cache clears the negative entry at once
+	c.IsProcessed(ctx, "other")
+	c.MarkProcessed(ctx, "other", time.Hour)
+	if processed, _ := c.IsProcessed(ctx, "other"); !processed {
+		t.Error("key marked through the cache is hidden by its negative entry")
+	}
+}
+
+func TestTieredCacheWritesThrough(t *testing.T) {
+	ctx := context.Background()
+	remote := newFakeRemote()
+	c := newTestTieredCache(t, remote, time.Minute)
+
+	if err := c.MarkProcessed(ctx, "key", time.Hour); err != nil {
+		t.Fatalf("MarkProcessed returned error: %v", err)
+	}
+	if !remote.has("key") {
+		t.Error("mark was not written to the remote tier")
+	}
+	if entry, _ := c.local.Lookup(ctx, "key"); !entry.Exists || entry.TTL > defaultTieredLocalTTL {
+		t.Errorf("local entry = %+v, want it capped at the local TTL", entry)
+	}
+
+	remote.err = errors.New("remote down")
+	if err := c.MarkProcessed(ctx, "failed", time.Hour); err == nil {
+		t.Fatal("MarkProcessed returned no error while the remote tier fails")
+	}
+	if entry, _ := c.local.Lookup(ctx, "failed"); entry.Exists {
+		t.Error("failed remote write was still marked locally")
+	}
+}
+
+func TestTieredCacheDelete(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("inspector remote", func(t *testing.T) {
+		remote := inspectableRemote{newFakeRemote()}
+		c := newTestTieredCache(t, remote, time.Minute)
+		c.MarkProcessed(ctx, "key", time.Hour)
+
+		if err := c.Delete(ctx, "key"); err != nil {
+			t.Fatalf("Delete returned error: %v", err)
+		}
+		if remote.has("key") {
+			t.Error("key was not deleted from the remote tier")
+		}
+		if processed, _ := c.IsProcessed(ctx, "key"); processed {
+			t.Error("deleted key is still processed")
+		}
+	})
+
+	t.Run("remote without inspector", func(t *testing.T) {
+		remote := newFakeRemote()
+		c := newTestTieredCache(t, remote, time.Minute)
+		c.MarkProcessed(ctx, "key", time.Hour)
+
+		if err := c.Delete(ctx, "key"); !errors.Is(err, ErrNotSupported) {
+			t.Fatalf("Delete error = %v, want ErrNotSupported", err)
+		}
+		if entry, _ := c.local.Lookup(ctx, "key"); entry.Exists {
+			t.Error("key was not deleted from the local tier")
+		}
+	})
+}
+
+func TestTieredCacheStatsAndLookup(t *testing.T) {
+	ctx := context.Background()
+	remote := inspectableRemote{newFakeRemote()}
+	c := newTestTieredCache(t, remote, time.Minute)
+
+	c.MarkProcessed(ctx, "local", time.Hour)
+	remote.MarkProcessed(ctx, "remote", time.Hour)
+
+	c.IsProcessed(ctx, "local")   // local hit
+	c.IsProcessed(ctx, "remote")  // remote hit
+	c.IsProcessed(ctx, "missing") // remote miss
+	c.IsProcessed(ctx, "missing") // negative miss
+
+	remote.err = errors.New("remote down")
+	if _, err := c.IsProcessed(ctx, "down"); err == nil {
+		t.Error("IsProcessed returned no error while the remote tier fails")
+	}
+	remote.err = nil
+
+	stats, err := c.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Stats returned error: %v", err)
+	}
+	if stats.Type != "tiered" || stats.Hits != 2 || stats.Misses != 2 || stats.Errors != 1 {
+		t.Errorf("Stats() = %+v, want 2 hits, 2 misses and 1 error", stats)
+	}
+	if len(stats.Tiers) != 1 || stats.Tiers[0].Type != "memory" || stats.Size != -1 {
+		t.Errorf("Stats() tiers = %+v, size %d; want the local tier only and an unknown size", stats.Tiers, stats.Size)
+	}
+
+	// Lookup reads the remote tier, which is authoritative
+	if entry, _ := c.Lookup(ctx, "remote"); !entry.Exists {
+		t.Error("Lookup of a remote key reported it missing")
+	}
+	remote.Delete(ctx, "remote")
+	if entry, _ := c.Lookup(ctx, "remote"); entry.Exists {
+		t.Error("Lookup reported a key deleted from the remote tier")
+	}
+}
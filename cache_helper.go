@@ -1,8 +1,6 @@
 package alchemywebhook
 
 import (
-	"crypto/tls"
-
 	"github.com/dawitel/alchemy-webhook/cache"
 )
 
@@ -20,34 +18,37 @@ func newCache(cfg CacheConfig) (cache.Cache, error) {
 			CleanupInterval: cfg.Memory.CleanupInterval,
 			EnableLRU:       cfg.Memory.EnableLRU,
 		},
+		Tiered: cache.TieredConfig{
+			LocalTTL:    cfg.Tiered.LocalTTL,
+			NegativeTTL: cfg.Tiered.NegativeTTL,
+		},
 	}
 
-	if cfg.Type == "redis" {
-		var tlsConfig *tls.Config
-		if cfg.Redis.EnableTLS {
-			if cfg.Redis.TLSConfig != nil {
-				tlsConfig = cfg.Redis.TLSConfig
-			} else {
-				tlsConfig = &tls.Config{
-					InsecureSkipVerify: cfg.Redis.TLSSkipVerify,
-				}
-			}
-		}
-
-		cacheCfg.Redis = cache.RedisConfig{
-			Address:       cfg.Redis.Address,
-			Password:      cfg.Redis.Password,
-			DB:            cfg.Redis.DB,
-			PoolSize:      cfg.Redis.PoolSize,
-			MinIdleConns:  cfg.Redis.MinIdleConns,
-			DialTimeout:   cfg.Redis.DialTimeout,
-			ReadTimeout:   cfg.Redis.ReadTimeout,
-			WriteTimeout:  cfg.Redis.WriteTimeout,
-			EnableTLS:     cfg.Redis.EnableTLS,
-			TLSSkipVerify: cfg.Redis.TLSSkipVerify,
-			TLSConfig:     tlsConfig,
-		}
+	if cfg.Type == "redis" || cfg.Type == "tiered" {
+		cacheCfg.Redis = redisConfig(cfg.Redis)
 	}
 
 	return cache.NewCache(cacheCfg)
 }
+
+// redisConfig converts the Redis settings of the configuration for the
+// cache package, which also creates the checkpoint store's Redis client
+func redisConfig(cfg RedisConfig) cache.RedisConfig {
+	redisCfg := cache.RedisConfig{
+		Address:       cfg.Address,
+		Password:      cfg.Password,
+		DB:            cfg.DB,
+		PoolSize:      cfg.PoolSize,
+		MinIdleConns:  cfg.MinIdleConns,
+		DialTimeout:   cfg.DialTimeout,
+		ReadTimeout:   cfg.ReadTimeout,
+		WriteTimeout:  cfg.WriteTimeout,
+		EnableTLS:     cfg.EnableTLS,
+		TLSSkipVerify: cfg.TLSSkipVerify,
+	}
+	// A nil *tls.Config in the interface would hide the skip verify fallback
+	if cfg.TLSConfig != nil {
+		redisCfg.TLSConfig = cfg.TLSConfig
+	}
+	return redisCfg
+}
@@ -0,0 +1,141 @@
+package checkpoint
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FileStore is a Store that keeps all checkpoints in one JSON file. Every
+// change rewrites the file atomically through a temporary file.
+type FileStore struct {
+	mu          sync.Mutex
+	path        string
+	checkpoints map[string]Checkpoint
+}
+
+// NewFileStore creates a store backed by the file at path, loading the
+// checkpoints it already holds. A missing file is created on the first Put.
+func NewFileStore(path string) (*FileStore, error) {
+	s := &FileStore{
+		path:        path,
+		checkpoints: make(map[string]Checkpoint),
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint file: %w", err)
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &s.checkpoints); err != nil {
+			return nil, fmt.Errorf("failed to decode checkpoint file: %w", err)
+		}
+	}
+
+	return s, nil
+}
+
+// Get returns the checkpoint of key and whether it exists
+func (s *FileStore) Get(ctx context.Context, key string) (Checkpoint, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	checkpoint, ok := s.checkpoints[key]
+	return checkpoint, ok, nil
+}
+
+// Put inserts or replaces a checkpoint
+func (s *FileStore) Put(ctx context.Context, checkpoint Checkpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous, existed := s.checkpoints[checkpoint.Key]
+	s.checkpoints[checkpoint.Key] = checkpoint
+	if err := s.save(); err != nil {
+		if existed {
+			s.checkpoints[checkpoint.Key] = previous
+		} else {
+			delete(s.checkpoints, checkpoint.Key)
+		}
+		return err
+	}
+	return nil
+}
+
+// PutMany inserts or replaces several checkpoints with a single rewrite of
+// the file
+func (s *FileStore) PutMany(ctx context.Context, checkpoints []Checkpoint) error {
+	if len(checkpoints) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous := maps.Clone(s.checkpoints)
+	for _, checkpoint := range checkpoints {
+		s.checkpoints[checkpoint.Key] = checkpoint
+	}
+	if err := s.save(); err != nil {
+		s.checkpoints = previous
+		return err
+	}
+	return nil
+}
+
+// Delete removes a checkpoint
+func (s *FileStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous, existed := s.checkpoints[key]
+	if !existed {
+		return nil
+	}
+	delete(s.checkpoints, key)
+	if err := s.save(); err != nil {
+		s.checkpoints[key] = previous
+		return err
+	}
+	return nil
+}
+
+// save writes all checkpoints to a temporary file and renames it over the
+// store file
+func (s *FileStore) save() error {
+	data, err := json.Marshal(s.checkpoints)
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoints: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary checkpoint file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write checkpoint file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync checkpoint file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close checkpoint file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to replace checkpoint file: %w", err)
+	}
+	return nil
+}
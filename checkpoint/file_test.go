@@ -0,0 +1,50 @@
+package checkpoint
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileStorePersistsCheckpoints(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoints.json")
+	ctx := context.Background()
+
+	store, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("NewFileStore returned error: %v", err)
+	}
+	if _, ok, _ := store.Get(ctx, "eth:incoming:0xabc"); ok {
+		t.Fatal("new store already holds a checkpoint")
+	}
+
+	updatedAt := time.Unix(1700000000, 0).UTC()
+	for _, checkpoint := range []Checkpoint{
+		{Key: "eth:incoming:0xabc", Height: 100, UpdatedAt: updatedAt},
+		{Key: "eth:incoming:0xabc", Height: 250, UpdatedAt: updatedAt},
+		{Key: "sol:incoming:Abc", Height: 7},
+	} {
+		if err := store.Put(ctx, checkpoint); err != nil {
+			t.Fatalf("Put returned error: %v", err)
+		}
+	}
+	if err := store.Delete(ctx, "sol:incoming:Abc"); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+
+	reopened, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("reopening store returned error: %v", err)
+	}
+	got, ok, err := reopened.Get(ctx, "eth:incoming:0xabc")
+	if err != nil || !ok {
+		t.Fatalf("Get = %v, %v; want the stored checkpoint", ok, err)
+	}
+	if got.Height != 250 || !got.UpdatedAt.Equal(updatedAt) {
+		t.Errorf("checkpoint = %+v, want height 250 updated at %v", got, updatedAt)
+	}
+	if _, ok, _ := reopened.Get(ctx, "sol:incoming:Abc"); ok {
+		t.Error("deleted checkpoint was persisted")
+	}
+}
@@ -0,0 +1,58 @@
+package checkpoint
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryStore is an in-memory Store implementation. Checkpoints survive
+// cancelled runs but not restarts.
+type MemoryStore struct {
+	mu          sync.RWMutex
+	checkpoints map[string]Checkpoint
+}
+
+// NewMemoryStore creates a new in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		checkpoints: make(map[string]Checkpoint),
+	}
+}
+
+// Get returns the checkpoint of key and whether it exists
+func (s *MemoryStore) Get(ctx context.Context, key string) (Checkpoint, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	checkpoint, ok := s.checkpoints[key]
+	return checkpoint, ok, nil
+}
+
+// Put inserts or replaces a checkpoint
+func (s *MemoryStore) Put(ctx context.Context, checkpoint Checkpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.checkpoints[checkpoint.Key] = checkpoint
+	return nil
+}
+
+// PutMany inserts or replaces several checkpoints
+func (s *MemoryStore) PutMany(ctx context.Context, checkpoints []Checkpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, checkpoint := range checkpoints {
+		s.checkpoints[checkpoint.Key] = checkpoint
+	}
+	return nil
+}
+
+// Delete removes a checkpoint
+func (s *MemoryStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.checkpoints, key)
+	return nil
+}
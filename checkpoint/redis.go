@@ -0,0 +1,89 @@
+package checkpoint
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const defaultRedisPrefix = "alchemy_webhook:checkpoint:"
+
+// RedisStore is a Redis-based Store implementation keeping one JSON value
+// per checkpoint
+type RedisStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewRedisStore creates a store on client. An empty prefix uses
+// "alchemy_webhook:checkpoint:".
+func NewRedisStore(client redis.UniversalClient, prefix string) *RedisStore {
+	if prefix == "" {
+		prefix = defaultRedisPrefix
+	}
+
+	return &RedisStore{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+// Get returns the checkpoint of key and whether it exists
+func (s *RedisStore) Get(ctx context.Context, key string) (Checkpoint, bool, error) {
+	data, err := s.client.Get(ctx, s.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return Checkpoint{}, false, nil
+	}
+	if err != nil {
+		return Checkpoint{}, false, fmt.Errorf("failed to get checkpoint: %w", err)
+	}
+
+	var checkpoint Checkpoint
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return Checkpoint{}, false, fmt.Errorf("failed to decode checkpoint: %w", err)
+	}
+	return checkpoint, true, nil
+}
+
+// Put inserts or replaces a checkpoint
+func (s *RedisStore) Put(ctx context.Context, checkpoint Checkpoint) error {
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint: %w", err)
+	}
+	if err := s.client.Set(ctx, s.prefix+checkpoint.Key, data, 0).Err(); err != nil {
+		return fmt.Errorf("failed to put checkpoint: %w", err)
+	}
+	return nil
+}
+
+// PutMany inserts or replaces several checkpoints with one MSET
+func (s *RedisStore) PutMany(ctx context.Context, checkpoints []Checkpoint) error {
+	if len(checkpoints) == 0 {
+		return nil
+	}
+
+	pairs := make([]interface{}, 0, 2*len(checkpoints))
+	for _, checkpoint := range checkpoints {
+		data, err := json.Marshal(checkpoint)
+		if err != nil {
+			return fmt.Errorf("failed to encode checkpoint: %w", err)
+		}
+		pairs = append(pairs, s.prefix+checkpoint.Key, data)
+	}
+	if err := s.client.MSet(ctx, pairs...).Err(); err != nil {
+		return fmt.Errorf("failed to put checkpoints: %w", err)
+	}
+	return nil
+}
+
+// Delete removes a checkpoint
+func (s *RedisStore) Delete(ctx context.Context, key string) error {
+	if err := s.client.Del(ctx, s.prefix+key).Err(); err != nil {
+		return fmt.Errorf("failed to delete checkpoint: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package checkpoint
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Checkpoint records how far a backfill got for one address and query.
+// Pages are read in ascending block or slot order, so the height is enough
+// to resume; provider page keys are tied to the exact query, which changes
+// as the chain head moves.
+type Checkpoint struct {
+	Key       string // Chain, direction and address, see Key
+	Height    uint64 // Last block or slot whose transfers were all backfilled
+	Cursor    string `json:",omitempty"` // Last item backfilled, e.g. a Solana signature
+	UpdatedAt time.Time
+}
+
+// Store persists backfill checkpoints
+type Store interface {
+	// Get returns the checkpoint of key and whether it exists
+	Get(ctx context.Context, key string) (Checkpoint, bool, error)
+
+	// Put inserts or replaces a checkpoint
+	Put(ctx context.Context, checkpoint Checkpoint) error
+
+	// PutMany inserts or replaces several checkpoints in one write
+	PutMany(ctx context.Context, checkpoints []Checkpoint) error
+
+	// Delete removes a checkpoint
+	Delete(ctx context.Context, key string) error
+}
+
+// Key returns the checkpoint key of an address, e.g.
+// "eth-mainnet:incoming:0xabc...". Addresses are used as given, so callers
+// normalize case-insensitive addresses first.
+func Key(chain, direction, address string) string {
+	return fmt.Sprintf("%s:%s:%s", chain, direction, address)
+}
@@ -0,0 +1,75 @@
+package checkpoint
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testStore checks the behaviour every Store implementation shares
+func testStore(t *testing.T, store Store) {
+	t.Helper()
+	ctx := context.Background()
+
+	updatedAt := time.Unix(1700000000, 0).UTC()
+	if err := store.Put(ctx, Checkpoint{Key: Key("eth-mainnet", "incoming", "0xabc"), Height: 10, UpdatedAt: updatedAt}); err != nil {
+		t.Fatalf("Put returned error: %v", err)
+	}
+	if err := store.PutMany(ctx, []Checkpoint{
+		{Key: Key("eth-mainnet", "incoming", "0xabc"), Height: 20, UpdatedAt: updatedAt},
+		{Key: Key("eth-mainnet", "outgoing", "0xabc"), Height: 30, UpdatedAt: updatedAt},
+		{Key: Key("sol-mainnet", "incoming", "Abc"), Height: 40, UpdatedAt: updatedAt},
+	}); err != nil {
+		t.Fatalf("PutMany returned error: %v", err)
+	}
+	if err := store.PutMany(ctx, nil); err != nil {
+		t.Fatalf("PutMany of no checkpoints returned error: %v", err)
+	}
+
+	for key, want := range map[string]uint64{
+		"eth-mainnet:incoming:0xabc": 20,
+		"eth-mainnet:outgoing:0xabc": 30,
+		"sol-mainnet:incoming:Abc":   40,
+	} {
+		got, ok, err := store.Get(ctx, key)
+		if err != nil || !ok {
+			t.Fatalf("Get(%s) = %v, %v; want the stored checkpoint", key, ok, err)
+		}
+		if got.Height != want || !got.UpdatedAt.Equal(updatedAt) {
+			t.Errorf("Get(%s) = %+v, want height %d updated at %v", key, got, want, updatedAt)
+		}
+	}
+
+	if err := store.Delete(ctx, "sol-mainnet:incoming:Abc"); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	if _, ok, err := store.Get(ctx, "sol-mainnet:incoming:Abc"); ok || err != nil {
+		t.Errorf("Get of a deleted checkpoint = %v, %v; want not found", ok, err)
+	}
+	if err := store.Delete(ctx, "missing"); err != nil {
+		t.Errorf("Delete of a missing checkpoint returned error: %v", err)
+	}
+}
+
+func TestStores(t *testing.T) {
+	tests := []struct {
+		name  string
+		store func(t *testing.T) Store
+	}{
+		{"memory", func(t *testing.T) Store { return NewMemoryStore() }},
+		{"file", func(t *testing.T) Store {
+			store, err := NewFileStore(filepath.Join(t.TempDir(), "checkpoints.json"))
+			if err != nil {
+				t.Fatalf("NewFileStore returned error: %v", err)
+			}
+			return store
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testStore(t, tt.store(t))
+		})
+	}
+}
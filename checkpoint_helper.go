@@ -0,0 +1,40 @@
+package alchemywebhook
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/dawitel/alchemy-webhook/cache"
+	"github.com/dawitel/alchemy-webhook/checkpoint"
+)
+
+// newCheckpointStore creates the backfill checkpoint store from the
+// configuration, or nil when checkpoints are disabled. The returned closer,
+// nil when there is nothing to release, closes the store's connections.
+func newCheckpointStore(cfg CheckpointConfig, cacheRedis RedisConfig) (checkpoint.Store, io.Closer, error) {
+	switch cfg.Type {
+	case "":
+		return nil, nil, nil
+	case "memory":
+		return checkpoint.NewMemoryStore(), nil, nil
+	case "file":
+		store, err := checkpoint.NewFileStore(cfg.Path)
+		if err != nil {
+			return nil, nil, err
+		}
+		return store, nil, nil
+	case "redis":
+		redisCfg := cfg.Redis
+		if redisCfg.Address == "" {
+			redisCfg = cacheRedis
+		}
+
+		client, err := cache.NewRedisClient(redisConfig(redisCfg))
+		if err != nil {
+			return nil, nil, err
+		}
+		return checkpoint.NewRedisStore(client, ""), client, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown checkpoint type: %s", cfg.Type)
+	}
+}
@@ -3,13 +3,17 @@ package alchemywebhook
 import (
 	"context"
 	"fmt"
+	"io"
 	"net/http"
 	"sync"
 	"time"
 
+	"github.com/dawitel/alchemy-webhook/backfill"
 	"github.com/dawitel/alchemy-webhook/cache"
 	"github.com/dawitel/alchemy-webhook/eth"
+	"github.com/dawitel/alchemy-webhook/ratelimit"
 	"github.com/dawitel/alchemy-webhook/solana"
+	"github.com/dawitel/alchemy-webhook/watch"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/rs/zerolog"
 )
@@ -40,14 +44,27 @@ type Client interface {
 	// GetWebhookAddresses gets addresses for a webhook
 	GetWebhookAddresses(ctx context.Context, webhookID string) ([]string, error)
 
-	// Backfill triggers manual backfill (returns error if disabled)
-	Backfill(ctx context.Context, addresses []string) error
+	// Backfill starts a backfill and returns its job (returns error if
+	// disabled, or the running job with backfill.ErrInProgress)
+	Backfill(ctx context.Context, addresses []string) (*backfill.Job, error)
 
 	// AddAddresses adds addresses to webhook
 	AddAddresses(ctx context.Context, webhookID string, addresses []string) error
 
 	// RemoveAddresses removes addresses from webhook
 	RemoveAddresses(ctx context.Context, webhookID string, addresses []string) error
+
+	// CacheStats returns dedupe cache statistics
+	CacheStats(ctx context.Context) (cache.Stats, error)
+
+	// LookupDedupeKey returns the state of a single dedupe key
+	LookupDedupeKey(ctx context.Context, key string) (cache.Entry, error)
+
+	// DeleteDedupeKey removes a single dedupe key so the transaction is processed again
+	DeleteDedupeKey(ctx context.Context, key string) error
+
+	// WatchedAddresses returns the set of addresses watched by the webhooks
+	WatchedAddresses() *watch.Set
 }
 
 // BaseClient is the base implementation of Client
@@ -58,6 +75,9 @@ type BaseClient struct {
 	handler        *Handler
 	backfill       Backfill
 	cache          cache.Cache
+	checkpoints    io.Closer // Connections of the checkpoint store, if any
+	watched        *watch.Set
+	webhookAddrs   *watch.Registry
 	mu             sync.RWMutex
 	started        bool
 	ctx            context.Context
@@ -75,6 +95,7 @@ type EthereumClient struct {
 type SolanaClient struct {
 	*BaseClient
 	Processor *solana.Processor
+	rpcClient *solana.RPCClient
 }
 
 // NewEthereumClient creates a new Ethereum client
@@ -88,29 +109,91 @@ func NewEthereumClient(cfg *Config, logger zerolog.Logger) (*EthereumClient, err
 		return nil, fmt.Errorf("failed to create cache: %w", err)
 	}
 
+	rpcURL := cfg.RPCURL
+	if rpcURL == "" && cfg.Backfill.Enabled {
+		rpcURL = cfg.Backfill.RPCURL
+	}
+
 	var rpcClient *ethclient.Client
-	if cfg.Backfill.Enabled && cfg.Backfill.RPCURL != "" {
-		client, err := ethclient.Dial(cfg.Backfill.RPCURL)
+	if rpcURL != "" {
+		client, err := ethclient.Dial(rpcURL)
 		if err != nil {
 			return nil, fmt.Errorf("failed to connect to Ethereum RPC: %w", err)
 		}
 		rpcClient = client
 	}
 
+	addressFormat, err := eth.ParseAddressFormat(cfg.AddressManagement.AddressFormat)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	// Lookups on the webhook path add RPC round-trips to every request, so
+	// they are only made when enabled explicitly, not whenever an RPC URL
+	// is set for backfill
+	var lookupClient *ethclient.Client
+	if cfg.EnrichWebhooks {
+		lookupClient = rpcClient
+	}
+
+	watched := watch.NewSet(watch.LowerCase)
+	opts := []eth.ProcessorOption{
+		eth.WithTokenRegistry(eth.NewTokenRegistry(lookupClient, nil, eth.TokenPolicy{})),
+		eth.WithAddressSet(watched),
+		eth.WithAddressFormat(addressFormat),
+	}
+	if !cfg.LegacyEventIDsUntil.IsZero() {
+		opts = append(opts, eth.WithLegacyEventIDs(cfg.LegacyEventIDsUntil))
+	}
+	if cfg.EnrichReceipts && rpcClient != nil {
+		opts = append(opts, eth.WithReceiptEnricher(eth.NewReceiptEnricher(rpcClient, 0, logger)))
+	} else if lookupClient != nil {
+		opts = append(opts, eth.WithBlockTimes(eth.NewBlockTimes(lookupClient, 0, logger)))
+	}
+
 	processor := eth.NewProcessor(
 		logger,
 		cacheInstance,
 		map[string]string{},
 		nil,
 		"eth-mainnet",
+		opts...,
 	)
 
 	network := "ETH_MAINNET"
 	webhookManager := NewWebhookManager(cfg, logger, network)
+	webhookManager.SetAddressNormalizer(addressFormat.Normalize)
 	verifier := NewVerifier(cfg.SignatureSecret)
 	handler := NewEthereumHandler(verifier, processor, logger, cfg.HTTPClient.MaxRequestBodySize)
-	var backfill Backfill = NewNoOpBackfill()
+	var historical Backfill = NewNoOpBackfill()
+	var checkpointCloser io.Closer
 	if cfg.Backfill.Enabled && rpcClient != nil {
+		direction, err := eth.ParseBackfillDirection(cfg.Backfill.Direction)
+		if err != nil {
+			return nil, fmt.Errorf("invalid config: %w", err)
+		}
+		backfillOpts := []eth.BackfillOption{eth.WithDirection(direction)}
+		if cfg.Backfill.ConfirmationDepth > 0 {
+			backfillOpts = append(backfillOpts, eth.WithConfirmationDepth(cfg.Backfill.ConfirmationDepth))
+		}
+		if cfg.Backfill.MaxPages > 0 {
+			backfillOpts = append(backfillOpts, eth.WithPageLimit(cfg.Backfill.MaxPages))
+		}
+		if cfg.Backfill.Workers > 0 {
+			backfillOpts = append(backfillOpts, eth.WithWorkers(cfg.Backfill.Workers))
+		}
+		if cfg.Backfill.RequestsPerSecond > 0 {
+			burst := max(int(cfg.Backfill.RequestsPerSecond), 1)
+			backfillOpts = append(backfillOpts, eth.WithRateLimit(cfg.Backfill.RequestsPerSecond, burst))
+		}
+		checkpoints, closer, err := newCheckpointStore(cfg.Backfill.Checkpoint, cfg.Cache.Redis)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create checkpoint store: %w", err)
+		}
+		if checkpoints != nil {
+			backfillOpts = append(backfillOpts, eth.WithCheckpoints(checkpoints))
+		}
+		checkpointCloser = closer
 		ethBackfill := eth.NewBackfill(
 			rpcClient,
 			processor,
@@ -118,8 +201,9 @@ func NewEthereumClient(cfg *Config, logger zerolog.Logger) (*EthereumClient, err
 			cacheInstance,
 			cfg.Backfill.TimeRange,
 			cfg.Backfill.BatchSize,
+			backfillOpts...,
 		)
-		backfill = ethBackfill
+		historical = ethBackfill
 	}
 
 	baseClient := &BaseClient{
@@ -127,8 +211,11 @@ func NewEthereumClient(cfg *Config, logger zerolog.Logger) (*EthereumClient, err
 		logger:         logger,
 		webhookManager: webhookManager,
 		handler:        handler,
-		backfill:       backfill,
+		backfill:       historical,
 		cache:          cacheInstance,
+		checkpoints:    checkpointCloser,
+		watched:        watched,
+		webhookAddrs:   watch.NewRegistry(watched),
 	}
 
 	return &EthereumClient{
@@ -149,36 +236,54 @@ func NewSolanaClient(cfg *Config, logger zerolog.Logger) (*SolanaClient, error)
 		return nil, fmt.Errorf("failed to create cache: %w", err)
 	}
 
+	var rpcClient *solana.RPCClient
+	if cfg.RPCURL != "" {
+		rpcClient = solana.NewRPCClient(cfg.RPCURL, &http.Client{Timeout: cfg.HTTPClient.Timeout})
+	}
+
+	watched := watch.NewSet(watch.Exact)
+	opts := []solana.ProcessorOption{
+		solana.WithAddressSet(watched),
+	}
+	if cfg.EnrichWebhooks && rpcClient != nil {
+		opts = append(opts, solana.WithBlockTimes(solana.NewBlockTimes(rpcClient, 0, logger)))
+	}
+
 	processor := solana.NewProcessor(
 		logger,
 		cacheInstance,
 		map[string]string{},
 		nil,
 		"sol-mainnet",
+		opts...,
 	)
 
 	network := "SOLANA_MAINNET"
 	webhookManager := NewWebhookManager(cfg, logger, network)
 	verifier := NewVerifier(cfg.SignatureSecret)
 	handler := NewSolanaHandler(verifier, processor, logger, cfg.HTTPClient.MaxRequestBodySize)
-	var backfill Backfill = NewNoOpBackfill()
-	if cfg.Backfill.Enabled && cfg.Backfill.HeliusAPIKey != "" {
-		httpClient := &http.Client{Timeout: cfg.HTTPClient.Timeout}
-		heliusURL := cfg.Backfill.HeliusURL
-		if heliusURL == "" {
-			heliusURL = "https://mainnet.helius-rpc.com"
+	var historical Backfill = NewNoOpBackfill()
+	var checkpointCloser io.Closer
+	if source := newSolanaSource(cfg, rpcClient); cfg.Backfill.Enabled && source != nil {
+		var backfillOpts []solana.BackfillOption
+		checkpoints, closer, err := newCheckpointStore(cfg.Backfill.Checkpoint, cfg.Cache.Redis)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create checkpoint store: %w", err)
 		}
+		if checkpoints != nil {
+			backfillOpts = append(backfillOpts, solana.WithCheckpoints(checkpoints))
+		}
+		checkpointCloser = closer
 		solBackfill := solana.NewBackfill(
-			cfg.Backfill.HeliusAPIKey,
-			heliusURL,
+			source,
 			processor,
 			logger,
 			cacheInstance,
 			cfg.Backfill.TimeRange,
 			cfg.Backfill.BatchSize,
-			httpClient,
+			backfillOpts...,
 		)
-		backfill = solBackfill
+		historical = solBackfill
 	}
 
 	baseClient := &BaseClient{
@@ -186,16 +291,43 @@ func NewSolanaClient(cfg *Config, logger zerolog.Logger) (*SolanaClient, error)
 		logger:         logger,
 		webhookManager: webhookManager,
 		handler:        handler,
-		backfill:       backfill,
+		backfill:       historical,
 		cache:          cacheInstance,
+		checkpoints:    checkpointCloser,
+		watched:        watched,
+		webhookAddrs:   watch.NewRegistry(watched),
 	}
 
 	return &SolanaClient{
 		BaseClient: baseClient,
 		Processor:  processor,
+		rpcClient:  rpcClient,
 	}, nil
 }
 
+// newSolanaSource returns the Solana backfill source: Helius when an API key
+// is set, otherwise standard RPC methods on Backfill.RPCURL or the client's
+// RPC node. It returns nil when neither is configured.
+func newSolanaSource(cfg *Config, rpcClient *solana.RPCClient) solana.Source {
+	rps := cfg.Backfill.RequestsPerSecond
+	if rps <= 0 {
+		rps = DefaultBackfillRequestsPerSecondSOL
+	}
+	limiter := ratelimit.New(rps, max(int(rps), 1))
+
+	if cfg.Backfill.HeliusAPIKey != "" {
+		httpClient := &http.Client{Timeout: cfg.HTTPClient.Timeout}
+		return solana.NewHeliusSource(cfg.Backfill.HeliusAPIKey, cfg.Backfill.HeliusURL, httpClient, limiter)
+	}
+	if cfg.Backfill.RPCURL != "" {
+		rpcClient = solana.NewRPCClient(cfg.Backfill.RPCURL, &http.Client{Timeout: cfg.HTTPClient.Timeout})
+	}
+	if rpcClient == nil {
+		return nil
+	}
+	return solana.NewRPCSource(rpcClient, limiter)
+}
+
 // Start initializes and starts the client
 func (c *BaseClient) Start(ctx context.Context) error {
 	c.mu.Lock()
@@ -210,6 +342,13 @@ func (c *BaseClient) Start(ctx context.Context) error {
 
 	c.logger.Info().Msg("Alchemy webhook SDK client started")
 
+	// Activities are classified against the watched set, so it is loaded
+	// before webhooks are handled; failures are retried on UpdateInterval
+	if err := c.loadWatchedAddresses(ctx); err != nil {
+		c.logger.Warn().Err(err).Msg("Failed to load watched addresses, retrying")
+	}
+	go c.refreshWatchedAddresses(c.ctx)
+
 	if c.cfg.Backfill.Enabled && c.cfg.Backfill.StartDelay > 0 {
 		go func() {
 			select {
@@ -221,7 +360,11 @@ func (c *BaseClient) Start(ctx context.Context) error {
 					for _, webhook := range webhooks {
 						addresses, err := c.webhookManager.GetWebhookAddresses(c.ctx, webhook.ID)
 						if err == nil && len(addresses) > 0 {
-							if err := c.backfill.Backfill(c.ctx, addresses); err != nil {
+							job, err := c.backfill.Backfill(c.ctx, addresses)
+							if err == nil {
+								err = job.Wait(c.ctx)
+							}
+							if err != nil {
 								c.logger.Warn().Err(err).Msg("Backfill failed")
 							}
 						}
@@ -234,6 +377,50 @@ func (c *BaseClient) Start(ctx context.Context) error {
 	return nil
 }
 
+// loadWatchedAddresses rebuilds the watched address set from the webhooks of the network
+func (c *BaseClient) loadWatchedAddresses(ctx context.Context) error {
+	webhooks, err := c.webhookManager.ListWebhooks(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list webhooks: %w", err)
+	}
+
+	sources := make(map[string][]string, len(webhooks))
+	for _, webhook := range webhooks {
+		addresses, err := c.webhookManager.GetWebhookAddresses(ctx, webhook.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get addresses of webhook %s: %w", webhook.ID, err)
+		}
+		sources[webhook.ID] = addresses
+	}
+
+	c.webhookAddrs.Load(sources)
+	c.logger.Info().Int("addresses", c.watched.Len()).Msg("Watched addresses loaded")
+	return nil
+}
+
+// refreshWatchedAddresses reloads the watched address set every
+// UpdateInterval, picking up changes made outside the client, until ctx is done
+func (c *BaseClient) refreshWatchedAddresses(ctx context.Context) {
+	interval := c.cfg.AddressManagement.UpdateInterval
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.loadWatchedAddresses(ctx); err != nil && ctx.Err() == nil {
+				c.logger.Warn().Err(err).Msg("Failed to refresh watched addresses")
+			}
+		}
+	}
+}
+
 // Stop gracefully stops the client
 func (c *BaseClient) Stop() error {
 	c.mu.Lock()
@@ -253,6 +440,12 @@ func (c *BaseClient) Stop() error {
 		}
 	}
 
+	if c.checkpoints != nil {
+		if err := c.checkpoints.Close(); err != nil {
+			c.logger.Warn().Err(err).Msg("Failed to close checkpoint store")
+		}
+	}
+
 	c.started = false
 	c.logger.Info().Msg("Alchemy webhook SDK client stopped")
 
@@ -283,7 +476,11 @@ func (c *BaseClient) CreateWebhook(ctx context.Context, name string) (string, er
 
 // UpdateWebhook updates webhook addresses
 func (c *BaseClient) UpdateWebhook(ctx context.Context, webhookID string, addressesToAdd, addressesToRemove []string) error {
-	return c.webhookManager.UpdateWebhookAddresses(ctx, webhookID, addressesToAdd, addressesToRemove)
+	if err := c.webhookManager.UpdateWebhookAddresses(ctx, webhookID, addressesToAdd, addressesToRemove); err != nil {
+		return err
+	}
+	c.webhookAddrs.Update(webhookID, addressesToAdd, addressesToRemove)
+	return nil
 }
 
 // ListWebhooks lists all webhooks
@@ -296,22 +493,31 @@ func (c *BaseClient) GetWebhookAddresses(ctx context.Context, webhookID string)
 	return c.webhookManager.GetWebhookAddresses(ctx, webhookID)
 }
 
-// Backfill triggers manual backfill
-func (c *BaseClient) Backfill(ctx context.Context, addresses []string) error {
+// Backfill starts a manual backfill and returns its job. The job runs in the
+// background until it ends, ctx is done or it is cancelled. While another
+// backfill runs, it returns that job with backfill.ErrInProgress, so the
+// caller can wait for it instead.
+func (c *BaseClient) Backfill(ctx context.Context, addresses []string) (*backfill.Job, error) {
 	if !c.cfg.Backfill.Enabled {
-		return fmt.Errorf("backfill is disabled")
+		return nil, fmt.Errorf("backfill is disabled")
 	}
 	return c.backfill.Backfill(ctx, addresses)
 }
 
 // AddAddresses adds addresses to webhook
 func (c *BaseClient) AddAddresses(ctx context.Context, webhookID string, addresses []string) error {
-	return c.webhookManager.UpdateWebhookAddresses(ctx, webhookID, addresses, nil)
+	return c.UpdateWebhook(ctx, webhookID, addresses, nil)
 }
 
 // RemoveAddresses removes addresses from webhook
 func (c *BaseClient) RemoveAddresses(ctx context.Context, webhookID string, addresses []string) error {
-	return c.webhookManager.UpdateWebhookAddresses(ctx, webhookID, nil, addresses)
+	return c.UpdateWebhook(ctx, webhookID, nil, addresses)
+}
+
+// WatchedAddresses returns the set of addresses watched by the webhooks.
+// Pass it to custom processors with eth.WithAddressSet or solana.WithAddressSet.
+func (c *BaseClient) WatchedAddresses() *watch.Set {
+	return c.watched
 }
 
 // GetCache returns the cache instance
@@ -319,6 +525,54 @@ func (c *BaseClient) GetCache() cache.Cache {
 	return c.cache
 }
 
+// CacheStats returns dedupe cache statistics
+func (c *BaseClient) CacheStats(ctx context.Context) (cache.Stats, error) {
+	provider, ok := c.cache.(cache.StatsProvider)
+	if !ok {
+		return cache.Stats{}, cache.ErrNotSupported
+	}
+	return provider.Stats(ctx)
+}
+
+// LookupDedupeKey returns the state of a single dedupe key
+func (c *BaseClient) LookupDedupeKey(ctx context.Context, key string) (cache.Entry, error) {
+	inspector, ok := c.cache.(cache.Inspector)
+	if !ok {
+		return cache.Entry{}, cache.ErrNotSupported
+	}
+	return inspector.Lookup(ctx, key)
+}
+
+// DeleteDedupeKey removes a single dedupe key so the transaction is processed again
+func (c *BaseClient) DeleteDedupeKey(ctx context.Context, key string) error {
+	inspector, ok := c.cache.(cache.Inspector)
+	if !ok {
+		return cache.ErrNotSupported
+	}
+
+	if err := inspector.Delete(ctx, key); err != nil {
+		return err
+	}
+
+	c.logger.Info().Str("key", key).Msg("Dedupe key deleted")
+	return nil
+}
+
+// RPCClient returns the Ethereum RPC client, or nil when no RPC URL is configured
+func (ec *EthereumClient) RPCClient() *ethclient.Client {
+	return ec.rpcClient
+}
+
+// BackfillRange backfills addresses over an explicit block or time range,
+// e.g. eth.WithTimeRange(start, end). Backfill must be enabled.
+func (ec *EthereumClient) BackfillRange(ctx context.Context, addresses []string, opts ...eth.RangeOption) (*eth.BackfillResult, error) {
+	ethBackfill, ok := ec.backfill.(*eth.Backfill)
+	if !ok {
+		return nil, fmt.Errorf("backfill not enabled")
+	}
+	return ethBackfill.BackfillRange(ctx, addresses, opts...)
+}
+
 // SetEthereumProcessor updates the Ethereum processor and handler
 func (ec *EthereumClient) SetEthereumProcessor(processor *eth.Processor) {
 	ec.mu.Lock()
@@ -328,6 +582,11 @@ func (ec *EthereumClient) SetEthereumProcessor(processor *eth.Processor) {
 	ec.handler = NewEthereumHandler(verifier, processor, ec.logger, ec.cfg.HTTPClient.MaxRequestBodySize)
 }
 
+// RPCClient returns the Solana RPC client configured via RPCURL, or nil
+func (sc *SolanaClient) RPCClient() *solana.RPCClient {
+	return sc.rpcClient
+}
+
 // SetSolanaProcessor updates the Solana processor and handler
 func (sc *SolanaClient) SetSolanaProcessor(processor *solana.Processor) {
 	sc.mu.Lock()
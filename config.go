@@ -5,6 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"time"
+
+	"github.com/dawitel/alchemy-webhook/eth"
 )
 
 const (
@@ -19,6 +21,9 @@ const (
 	DefaultBackfillBatchSize      = 100
 	DefaultBackfillStartDelay     = 30 * time.Second
 
+	// Solana backfill RPC calls per second when RequestsPerSecond is unset
+	DefaultBackfillRequestsPerSecondSOL = 10
+
 	// Circuit breaker defaults
 	DefaultCircuitBreakerMaxRequests = 5
 	DefaultCircuitBreakerInterval    = 60 * time.Second
@@ -55,6 +60,12 @@ type Config struct {
 	WebhookURL      string
 	SignatureSecret string
 
+	RPCURL         string // Chain JSON-RPC used for on-chain lookups such as token metadata and confirmations
+	EnrichReceipts bool   // Attach receipts and block timestamps to Ethereum activities; requires an RPC URL
+	EnrichWebhooks bool   // Look up token metadata and block times over RPC while handling webhooks; requires an RPC URL
+
+	LegacyEventIDsUntil time.Time // Also dedupe Ethereum activities against pre-event-ID keys until this time
+
 	Cache CacheConfig
 
 	Backfill BackfillConfig
@@ -73,9 +84,10 @@ type Config struct {
 // CacheConfig configures transaction caching
 type CacheConfig struct {
 	Enabled    bool
-	Type       string // "redis" or "memory"
+	Type       string // "redis", "memory" or "tiered"
 	Redis      RedisConfig
 	Memory     MemoryConfig
+	Tiered     TieredConfig
 	DefaultTTL time.Duration
 }
 
@@ -101,15 +113,37 @@ type MemoryConfig struct {
 	EnableLRU       bool
 }
 
+// TieredConfig configures the two-tier cache (memory in front of Redis)
+type TieredConfig struct {
+	LocalTTL    time.Duration // Maximum time an entry is held in memory, 10 minutes when zero
+	NegativeTTL time.Duration // How long a "not processed" answer from Redis is reused, 5 seconds when zero; negative disables
+}
+
 // BackfillConfig configures backfill functionality
 type BackfillConfig struct {
 	Enabled      bool
 	TimeRange    time.Duration
 	BatchSize    int
-	RPCURL       string // For Ethereum
-	HeliusAPIKey string // For Solana
+	RPCURL       string // For Ethereum, and for Solana without a Helius API key
+	HeliusAPIKey string // For Solana, optional
 	HeliusURL    string // For Solana
 	StartDelay   time.Duration
+
+	ConfirmationDepth uint64  // For Ethereum: most recent blocks left out of a backfill, 0 uses the default of 12
+	Workers           int     // For Ethereum: address batches fetched concurrently, 0 uses the default of 4
+	RequestsPerSecond float64 // Backfill RPC rate limit, 0 uses the default of 5 for Ethereum and 10 for Solana
+	Direction         string  // For Ethereum: "incoming" (default), "outgoing" or "both"
+	MaxPages          int     // For Ethereum: pages read per address batch and direction, 0 for no limit
+
+	Checkpoint CheckpointConfig
+}
+
+// CheckpointConfig configures where backfill progress is stored. With a
+// store, each run continues from where the last one stopped per address.
+type CheckpointConfig struct {
+	Type  string      // "memory", "file" or "redis", empty disables checkpoints
+	Path  string      // For "file": the JSON file holding the checkpoints
+	Redis RedisConfig // For "redis": the connection, an empty address uses Cache.Redis
 }
 
 // CircuitBreakerConfig configures circuit breaker
@@ -132,6 +166,7 @@ type RetryConfig struct {
 type AddressManagementConfig struct {
 	MaxAddressesPerWebhook int
 	UpdateInterval         time.Duration
+	AddressFormat          string // Ethereum address casing: "lowercase" (default) or "checksum" (EIP-55)
 }
 
 // HTTPClientConfig configures HTTP client
@@ -193,6 +228,7 @@ func NewConfig() *ConfigBuilder {
 			AddressManagement: AddressManagementConfig{
 				MaxAddressesPerWebhook: DefaultMaxAddressesPerWebhook,
 				UpdateInterval:         DefaultUpdateInterval,
+				AddressFormat:          "lowercase",
 			},
 			HTTPClient: HTTPClientConfig{
 				Timeout:            DefaultHTTPTimeout,
@@ -236,6 +272,39 @@ func (b *ConfigBuilder) WithSignatureSecret(secret string) *ConfigBuilder {
 	return b
 }
 
+// WithRPCURL sets the chain RPC URL used for on-chain lookups
+func (b *ConfigBuilder) WithRPCURL(url string) *ConfigBuilder {
+	b.config.RPCURL = url
+	return b
+}
+
+// WithReceiptEnrichment enables attaching receipts and block timestamps to Ethereum activities
+func (b *ConfigBuilder) WithReceiptEnrichment(enabled bool) *ConfigBuilder {
+	b.config.EnrichReceipts = enabled
+	return b
+}
+
+// WithWebhookEnrichment enables token metadata and block time lookups over
+// RPC on the webhook path. They add RPC round-trips to webhook handling, so
+// an RPC URL alone does not enable them.
+func (b *ConfigBuilder) WithWebhookEnrichment(enabled bool) *ConfigBuilder {
+	b.config.EnrichWebhooks = enabled
+	return b
+}
+
+// WithLegacyEventIDs dedupes Ethereum activities against the keys of
+// releases before event IDs until the given time, see eth.WithLegacyEventIDs
+func (b *ConfigBuilder) WithLegacyEventIDs(until time.Time) *ConfigBuilder {
+	b.config.LegacyEventIDsUntil = until
+	return b
+}
+
+// WithAddressFormat sets the casing of Ethereum addresses, "lowercase" or "checksum"
+func (b *ConfigBuilder) WithAddressFormat(format string) *ConfigBuilder {
+	b.config.AddressManagement.AddressFormat = format
+	return b
+}
+
 // WithCache sets the cache configuration
 func (b *ConfigBuilder) WithCache(cache CacheConfig) *ConfigBuilder {
 	b.config.Cache = cache
@@ -301,11 +370,11 @@ func (c *Config) Validate() error {
 	}
 
 	if c.Cache.Enabled {
-		if c.Cache.Type != "redis" && c.Cache.Type != "memory" {
-			return fmt.Errorf("invalid cache type: %s (must be 'redis' or 'memory')", c.Cache.Type)
+		if c.Cache.Type != "redis" && c.Cache.Type != "memory" && c.Cache.Type != "tiered" {
+			return fmt.Errorf("invalid cache type: %s (must be 'redis', 'memory' or 'tiered')", c.Cache.Type)
 		}
 
-		if c.Cache.Type == "redis" {
+		if c.Cache.Type == "redis" || c.Cache.Type == "tiered" {
 			if c.Cache.Redis.Address == "" {
 				return errors.New("Redis address is required when using Redis cache")
 			}
@@ -313,9 +382,34 @@ func (c *Config) Validate() error {
 	}
 
 	if c.Backfill.Enabled {
-		if c.Backfill.RPCURL == "" && c.Backfill.HeliusAPIKey == "" {
-			return errors.New("either RPCURL (for Ethereum) or HeliusAPIKey (for Solana) must be set when backfill is enabled")
+		if c.Backfill.RPCURL == "" && c.RPCURL == "" && c.Backfill.HeliusAPIKey == "" {
+			return errors.New("either RPCURL or HeliusAPIKey (for Solana) must be set when backfill is enabled")
+		}
+	}
+
+	if _, err := eth.ParseBackfillDirection(c.Backfill.Direction); err != nil {
+		return err
+	}
+
+	switch c.Backfill.Checkpoint.Type {
+	case "", "memory":
+	case "file":
+		if c.Backfill.Checkpoint.Path == "" {
+			return errors.New("checkpoint path is required when using file checkpoints")
 		}
+	case "redis":
+		if c.Backfill.Checkpoint.Redis.Address == "" && c.Cache.Redis.Address == "" {
+			return errors.New("Redis address is required when using Redis checkpoints")
+		}
+	default:
+		return fmt.Errorf("unknown checkpoint type: %s", c.Backfill.Checkpoint.Type)
+	}
+
+	if c.EnrichReceipts && c.RPCURL == "" && c.Backfill.RPCURL == "" {
+		return errors.New("RPCURL is required when receipt enrichment is enabled")
+	}
+	if c.EnrichWebhooks && c.RPCURL == "" && c.Backfill.RPCURL == "" {
+		return errors.New("RPCURL is required when webhook enrichment is enabled")
 	}
 
 	if c.CircuitBreaker.Threshold < 0 || c.CircuitBreaker.Threshold > 1 {
@@ -330,6 +424,10 @@ func (c *Config) Validate() error {
 		return errors.New("max addresses per webhook must be greater than 0")
 	}
 
+	if _, err := eth.ParseAddressFormat(c.AddressManagement.AddressFormat); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -0,0 +1,48 @@
+package confirmation
+
+import (
+	"context"
+	"slices"
+	"time"
+)
+
+// DropAfterMisses is how many consecutive checks must miss a transaction
+// before it is reported dropped. Nodes behind a load balancer can briefly
+// lag, so a single miss is not enough.
+const DropAfterMisses = 3
+
+// Record represents a processed transaction awaiting confirmations
+type Record struct {
+	ID        string // Event ID or signature used as the store key
+	TxHash    string // Transaction hash or signature
+	Height    uint64 // Block number or slot the transaction was included in
+	BlockHash string
+	Reached   int    // Number of thresholds already reported
+	Misses    int    // Consecutive checks that did not find the transaction
+	Payload   []byte // JSON encoded chain-specific event
+	CreatedAt time.Time
+}
+
+// Store persists records awaiting confirmations
+type Store interface {
+	// Put inserts or replaces a record
+	Put(ctx context.Context, record Record) error
+
+	// Delete removes a record
+	Delete(ctx context.Context, id string) error
+
+	// List returns all pending records
+	List(ctx context.Context) ([]Record, error)
+}
+
+// NormalizeThresholds returns the thresholds sorted ascending without zero or duplicate values
+func NormalizeThresholds(thresholds []uint64) []uint64 {
+	sorted := make([]uint64, 0, len(thresholds))
+	for _, threshold := range thresholds {
+		if threshold > 0 {
+			sorted = append(sorted, threshold)
+		}
+	}
+	slices.Sort(sorted)
+	return slices.Compact(sorted)
+}
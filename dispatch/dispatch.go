@@ -0,0 +1,231 @@
+package dispatch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// DefaultSubscriberName is the name under which a processor registers the
+// handler passed to its constructor
+const DefaultSubscriberName = "default"
+
+// RetryPolicy controls how often a failed delivery is retried
+type RetryPolicy struct {
+	MaxAttempts    int           // Total attempts including the first; values below 1 mean 1
+	InitialBackoff time.Duration // Delay before the second attempt
+	MaxBackoff     time.Duration // Upper bound for the doubling delay; zero means no bound
+}
+
+// Subscriber receives events from a Dispatcher
+type Subscriber[T any] struct {
+	Name     string
+	Handle   func(ctx context.Context, event T) error
+	Filter   func(event T) bool // Nil delivers every event
+	Timeout  time.Duration      // Per attempt; zero means no timeout
+	Retry    RetryPolicy
+	Optional bool // Failures are logged but do not fail the dispatch
+}
+
+// SubscriberError is the failure of a single subscriber
+type SubscriberError struct {
+	Name string
+	Err  error
+}
+
+// Error implements the error interface
+func (e *SubscriberError) Error() string {
+	return fmt.Sprintf("subscriber %s: %v", e.Name, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *SubscriberError) Unwrap() error {
+	return e.Err
+}
+
+// Dispatcher fans events out to named subscribers. Subscribers run
+// concurrently and in isolation: a failing, slow or panicking subscriber
+// does not affect the others.
+type Dispatcher[T any] struct {
+	mu          sync.RWMutex
+	subscribers []Subscriber[T]
+	logger      zerolog.Logger
+}
+
+// New creates an empty dispatcher
+func New[T any](logger zerolog.Logger) *Dispatcher[T] {
+	return &Dispatcher[T]{logger: logger}
+}
+
+// Subscribe registers a subscriber. Names must be unique.
+func (d *Dispatcher[T]) Subscribe(sub Subscriber[T]) error {
+	if sub.Name == "" {
+		return errors.New("subscriber name is required")
+	}
+	if sub.Handle == nil {
+		return fmt.Errorf("subscriber %s has no handle function", sub.Name)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, existing := range d.subscribers {
+		if existing.Name == sub.Name {
+			return fmt.Errorf("subscriber %s already registered", sub.Name)
+		}
+	}
+	d.subscribers = append(d.subscribers, sub)
+	return nil
+}
+
+// Unsubscribe removes a subscriber and reports whether it was registered
+func (d *Dispatcher[T]) Unsubscribe(name string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for i, sub := range d.subscribers {
+		if sub.Name == name {
+			d.subscribers = append(d.subscribers[:i:i], d.subscribers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Subscribers returns the names of the registered subscribers
+func (d *Dispatcher[T]) Subscribers() []string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	names := make([]string, len(d.subscribers))
+	for i, sub := range d.subscribers {
+		names[i] = sub.Name
+	}
+	return names
+}
+
+// Dispatch delivers event to every subscriber whose filter accepts it and
+// waits for all of them. The returned error joins the SubscriberErrors of
+// required subscribers; it is nil when all of them succeeded.
+func (d *Dispatcher[T]) Dispatch(ctx context.Context, event T) error {
+	d.mu.RLock()
+	subscribers := make([]Subscriber[T], 0, len(d.subscribers))
+	for _, sub := range d.subscribers {
+		if sub.Filter == nil || sub.Filter(event) {
+			subscribers = append(subscribers, sub)
+		}
+	}
+	d.mu.RUnlock()
+
+	if len(subscribers) == 1 {
+		return d.deliver(ctx, subscribers[0], event)
+	}
+
+	errs := make([]error, len(subscribers))
+	var wg sync.WaitGroup
+	for i, sub := range subscribers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = d.deliver(ctx, sub, event)
+		}()
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// deliver runs one subscriber with its retry policy. Failures of optional
+// subscribers are logged and swallowed.
+func (d *Dispatcher[T]) deliver(ctx context.Context, sub Subscriber[T], event T) error {
+	attempts := max(sub.Retry.MaxAttempts, 1)
+	backoff := sub.Retry.InitialBackoff
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = d.attempt(ctx, sub, event); err == nil {
+			return nil
+		}
+		if attempt >= attempts {
+			break
+		}
+
+		d.logger.Debug().
+			Err(err).
+			Str("subscriber", sub.Name).
+			Int("attempt", attempt).
+			Msg("Subscriber failed, retrying")
+
+		if sleepErr := sleep(ctx, backoff); sleepErr != nil {
+			err = sleepErr
+			break
+		}
+
+		backoff *= 2
+		if sub.Retry.MaxBackoff > 0 && backoff > sub.Retry.MaxBackoff {
+			backoff = sub.Retry.MaxBackoff
+		}
+	}
+
+	if sub.Optional {
+		d.logger.Warn().Err(err).Str("subscriber", sub.Name).Msg("Optional subscriber failed")
+		return nil
+	}
+	return &SubscriberError{Name: sub.Name, Err: err}
+}
+
+// attempt runs a single delivery, converting panics to errors. With a
+// timeout the attempt is abandoned when it expires, even if the subscriber
+// ignores its context.
+func (d *Dispatcher[T]) attempt(ctx context.Context, sub Subscriber[T], event T) error {
+	if sub.Timeout <= 0 {
+		return call(ctx, sub, event)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, sub.Timeout)
+	defer cancel()
+
+	result := make(chan error, 1)
+	go func() {
+		result <- call(ctx, sub, event)
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("subscriber timed out: %w", ctx.Err())
+	}
+}
+
+// call invokes the subscriber and recovers from panics
+func call[T any](ctx context.Context, sub Subscriber[T], event T) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("panic: %v", rec)
+		}
+	}()
+
+	return sub.Handle(ctx, event)
+}
+
+// sleep waits for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,82 @@
+package dispatch
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestDispatchIsolatesSubscribers(t *testing.T) {
+	d := New[int](zerolog.Nop())
+
+	var delivered, flakyCalls atomic.Int32
+	subscribers := []Subscriber[int]{
+		{Name: "ledger", Handle: func(ctx context.Context, event int) error {
+			delivered.Add(1)
+			return nil
+		}},
+		{Name: "panics", Optional: true, Handle: func(ctx context.Context, event int) error {
+			panic("boom")
+		}},
+		{Name: "slow", Optional: true, Timeout: 10 * time.Millisecond, Handle: func(ctx context.Context, event int) error {
+			time.Sleep(300 * time.Millisecond)
+			return nil
+		}},
+		{Name: "flaky", Retry: RetryPolicy{MaxAttempts: 3}, Handle: func(ctx context.Context, event int) error {
+			if flakyCalls.Add(1) < 3 {
+				return errors.New("temporary")
+			}
+			return nil
+		}},
+		{Name: "odd-only", Filter: func(event int) bool { return event%2 == 1 }, Handle: func(ctx context.Context, event int) error {
+			return errors.New("must not be called")
+		}},
+	}
+	for _, sub := range subscribers {
+		if err := d.Subscribe(sub); err != nil {
+			t.Fatalf("Subscribe(%s) returned error: %v", sub.Name, err)
+		}
+	}
+
+	start := time.Now()
+	if err := d.Dispatch(context.Background(), 2); err != nil {
+		t.Fatalf("Dispatch returned error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 200*time.Millisecond {
+		t.Errorf("Dispatch waited %s for a timed out subscriber", elapsed)
+	}
+	if delivered.Load() != 1 {
+		t.Errorf("ledger called %d times, want 1", delivered.Load())
+	}
+	if flakyCalls.Load() != 3 {
+		t.Errorf("flaky called %d times, want 3", flakyCalls.Load())
+	}
+}
+
+func TestDispatchReportsRequiredFailures(t *testing.T) {
+	d := New[int](zerolog.Nop())
+	errFailed := errors.New("failed")
+
+	d.Subscribe(Subscriber[int]{Name: "ok", Handle: func(ctx context.Context, event int) error { return nil }})
+	d.Subscribe(Subscriber[int]{Name: "required", Handle: func(ctx context.Context, event int) error { return errFailed }})
+
+	err := d.Dispatch(context.Background(), 1)
+	var subErr *SubscriberError
+	if !errors.As(err, &subErr) || subErr.Name != "required" || !errors.Is(err, errFailed) {
+		t.Fatalf("Dispatch error = %v, want failure of subscriber required", err)
+	}
+
+	if err := d.Subscribe(Subscriber[int]{Name: "ok", Handle: func(ctx context.Context, event int) error { return nil }}); err == nil {
+		t.Error("Subscribe accepted a duplicate name")
+	}
+	if !d.Unsubscribe("required") {
+		t.Error("Unsubscribe(required) = false, want true")
+	}
+	if err := d.Dispatch(context.Background(), 1); err != nil {
+		t.Errorf("Dispatch after Unsubscribe returned error: %v", err)
+	}
+}
@@ -0,0 +1,52 @@
+package eth
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// AddressFormat is the casing applied to the addresses of processed events
+type AddressFormat string
+
+// Address formats
+const (
+	AddressFormatLowercase AddressFormat = "lowercase" // 0x-prefixed lowercase hex
+	AddressFormatChecksum  AddressFormat = "checksum"  // EIP-55 mixed-case checksum
+)
+
+// ParseAddressFormat parses an address format name. An empty name is lowercase.
+func ParseAddressFormat(name string) (AddressFormat, error) {
+	switch AddressFormat(strings.ToLower(strings.TrimSpace(name))) {
+	case "", AddressFormatLowercase:
+		return AddressFormatLowercase, nil
+	case AddressFormatChecksum:
+		return AddressFormatChecksum, nil
+	default:
+		return "", fmt.Errorf("invalid address format: %s (must be 'lowercase' or 'checksum')", name)
+	}
+}
+
+// Normalize formats a hex address. Values that are not addresses are
+// returned trimmed but otherwise unchanged.
+func (f AddressFormat) Normalize(addr string) string {
+	addr = strings.TrimSpace(addr)
+	if !common.IsHexAddress(addr) {
+		return addr
+	}
+
+	if f == AddressFormatChecksum {
+		return common.HexToAddress(addr).Hex()
+	}
+	return "0x" + strings.ToLower(strings.TrimPrefix(strings.TrimPrefix(addr, "0x"), "0X"))
+}
+
+// WithAddressFormat sets the format of the from, to, contract and watched
+// addresses of processed activities. The default is lowercase. Dedupe keys
+// are built from lowercase transaction hashes and do not depend on it.
+func WithAddressFormat(format AddressFormat) ProcessorOption {
+	return func(p *Processor) {
+		p.addressFormat = format
+	}
+}
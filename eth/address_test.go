@@ -0,0 +1,79 @@
+package eth
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/rs/zerolog"
+)
+
+func TestAddressFormatNormalize(t *testing.T) {
+	const mixed = "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"
+	tests := []struct {
+		name   string
+		format AddressFormat
+		input  string
+		want   string
+	}{
+		{"lowercase", AddressFormatLowercase, mixed, "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"},
+		{"checksum", AddressFormatChecksum, "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed", mixed},
+		{"missing prefix", AddressFormatChecksum, "5aaeb6053f3e94c9b9a09f33669435e7ef1beaed", mixed},
+		{"surrounding space", AddressFormatLowercase, " " + mixed + " ", "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"},
+		{"not an address", AddressFormatChecksum, "vitalik.eth", "vitalik.eth"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.format.Normalize(tt.input); got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebhookAndBackfillAddressesMatch(t *testing.T) {
+	payload := `{"blockNum":"0x10","hash":"0x` + testHash + `","fromAddress":"0x` + testAddrA + `","toAddress":"0x` + testAddrB + `",
+		"value":1,"category":"external","rawContract":{"rawValue":"0xde0b6b3a7640000","decimals":18}}`
+	var webhookActivity AlchemyActivity
+	if err := json.Unmarshal([]byte(payload), &webhookActivity); err != nil {
+		t.Fatalf("failed to parse activity: %v", err)
+	}
+
+	value := json.Number("1")
+	backfillActivity := transferToActivity(AlchemyAssetTransfer{
+		BlockNum: "0x10",
+		UniqueID: "0x" + testHash + ":external",
+		Hash:     "0x" + testHash,
+		From:     "0x" + testAddrA,
+		To:       common.HexToAddress(testAddrB).Hex(),
+		Value:    &value,
+		Category: "external",
+	})
+	backfillActivity.RawContract = webhookActivity.RawContract
+
+	for _, format := range []AddressFormat{AddressFormatLowercase, AddressFormatChecksum} {
+		var got []ProcessedActivity
+		processor := NewProcessor(zerolog.Nop(), nil, nil, func(ctx context.Context, event ProcessedActivity) error {
+			got = append(got, event)
+			return nil
+		}, "eth-mainnet", WithAddressFormat(format))
+
+		for _, activity := range []AlchemyActivity{webhookActivity, backfillActivity} {
+			if err := processor.ProcessActivity(context.Background(), activity); err != nil {
+				t.Fatalf("%s: ProcessActivity returned error: %v", format, err)
+			}
+		}
+		if len(got) != 2 {
+			t.Fatalf("%s: got %d activities, want 2", format, len(got))
+		}
+		if got[0].FromAddress != got[1].FromAddress || got[0].ToAddress != got[1].ToAddress {
+			t.Errorf("%s: webhook %s -> %s, backfill %s -> %s", format,
+				got[0].FromAddress, got[0].ToAddress, got[1].FromAddress, got[1].ToAddress)
+		}
+		if want := format.Normalize(testAddrB); got[0].ToAddress != want {
+			t.Errorf("%s: to address = %s, want %s", format, got[0].ToAddress, want)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package eth
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// ethDecimals is the number of decimals of native ETH
+const ethDecimals = 18
+
+// maxDecimals is the largest number of decimals an ERC-20 token can declare
+const maxDecimals = 255
+
+// maxDecimalExponent bounds the exponent of a decimal value. Token amounts
+// stay far below it, while larger exponents would allocate huge numbers.
+const maxDecimalExponent = 100
+
+// parseRawValue parses a hex encoded raw amount such as "0x2386f26fc10000"
+func parseRawValue(raw string) (*big.Int, error) {
+	hexStr := strings.TrimPrefix(strings.TrimPrefix(raw, "0x"), "0X")
+	if hexStr == "" {
+		return big.NewInt(0), nil
+	}
+
+	amount, ok := new(big.Int).SetString(hexStr, 16)
+	if !ok {
+		return nil, fmt.Errorf("invalid raw value: %q", raw)
+	}
+	if amount.Sign() < 0 {
+		return nil, fmt.Errorf("negative raw value: %q", raw)
+	}
+	return amount, nil
+}
+
+// parseDecimalAmount converts a decimal string such as "1.5" or "1e-7" into
+// base units with the given number of decimals. Significant fractional
+// digits beyond decimals are truncated, which is reported by truncated.
+func parseDecimalAmount(value string, decimals int) (amount *big.Int, truncated bool, err error) {
+	if value == "" {
+		return nil, false, fmt.Errorf("decimal value is empty")
+	}
+	if decimals < 0 || decimals > maxDecimals {
+		return nil, false, fmt.Errorf("invalid decimals: %d", decimals)
+	}
+
+	mantissa := value
+	exponent := 0
+	if idx := strings.IndexAny(value, "eE"); idx >= 0 {
+		exp, err := strconv.Atoi(value[idx+1:])
+		if err != nil {
+			return nil, false, fmt.Errorf("invalid exponent in decimal value %q: %w", value, err)
+		}
+		if exp > maxDecimalExponent || exp < -maxDecimalExponent {
+			return nil, false, fmt.Errorf("exponent of decimal value %q out of range", value)
+		}
+		mantissa = value[:idx]
+		exponent = exp
+	}
+
+	mantissa = strings.TrimPrefix(mantissa, "+")
+	if strings.HasPrefix(mantissa, "-") {
+		return nil, false, fmt.Errorf("negative decimal value: %q", value)
+	}
+
+	intPart, fracPart, _ := strings.Cut(mantissa, ".")
+	digits := intPart + fracPart
+	if digits == "" {
+		return nil, false, fmt.Errorf("invalid decimal value: %q", value)
+	}
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			return nil, false, fmt.Errorf("invalid character in decimal value %q: %c", value, c)
+		}
+	}
+
+	shift := decimals + exponent - len(fracPart)
+	if shift < 0 {
+		cut := len(digits) + shift
+		if cut < 0 {
+			cut = 0
+		}
+		truncated = strings.TrimLeft(digits[cut:], "0") != ""
+		digits = digits[:cut]
+		shift = 0
+	}
+	if digits == "" {
+		return big.NewInt(0), truncated, nil
+	}
+
+	amount, ok := new(big.Int).SetString(digits, 10)
+	if !ok {
+		return nil, false, fmt.Errorf("invalid decimal value: %q", value)
+	}
+	if shift > 0 {
+		amount.Mul(amount, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(shift)), nil))
+	}
+	return amount, truncated, nil
+}
+
+// activityAmount returns the amount of an activity in base units.
+// rawContract.rawValue is the source of truth; the decimal value is only
+// used when no raw value was sent, and truncated reports whether it had
+// more fractional digits than decimals. A nil amount means neither is present.
+func activityAmount(activity AlchemyActivity, decimals int) (amount *big.Int, truncated bool, err error) {
+	if activity.RawContract != nil && activity.RawContract.RawValue != "" {
+		amount, err = parseRawValue(activity.RawContract.RawValue)
+		return amount, false, err
+	}
+	if activity.Value != nil {
+		return parseDecimalAmount(activity.Value.String(), decimals)
+	}
+	return nil, false, nil
+}
@@ -0,0 +1,166 @@
+package eth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestParseRawValue(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{raw: "0x0", want: "0"},
+		{raw: "0x", want: "0"},
+		{raw: "", want: "0"},
+		{raw: "0x2386f26fc10000", want: "10000000000000000"},
+		{raw: "2386f26fc10000", want: "10000000000000000"},
+		{raw: "0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", want: "115792089237316195423570985008687907853269984665640564039457584007913129639935"},
+		{raw: "0xzz", wantErr: true},
+		{raw: "-1", wantErr: true},
+		{raw: "0x-1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseRawValue(tt.raw)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseRawValue(%q) expected error, got %s", tt.raw, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseRawValue(%q) unexpected error: %v", tt.raw, err)
+			continue
+		}
+		if got.String() != tt.want {
+			t.Errorf("parseRawValue(%q) = %s, want %s", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestParseDecimalAmount(t *testing.T) {
+	tests := []struct {
+		value         string
+		decimals      int
+		want          string
+		wantTruncated bool
+		wantErr       bool
+	}{
+		{value: "0", decimals: 18, want: "0"},
+		{value: "1", decimals: 18, want: "1000000000000000000"},
+		{value: "1.5", decimals: 18, want: "1500000000000000000"},
+		{value: "0.000000000000000001", decimals: 18, want: "1"},
+		{value: "123456789.123456789012345678", decimals: 18, want: "123456789123456789012345678"},
+		{value: "1e-7", decimals: 18, want: "100000000000"},
+		{value: "1.5E3", decimals: 6, want: "1500000000"},
+		{value: "2.50000", decimals: 2, want: "250"},
+		{value: ".5", decimals: 1, want: "5"},
+		{value: "115792089237316195423570985008687907853269984665640564039457584007913129639935", decimals: 0, want: "115792089237316195423570985008687907853269984665640564039457584007913129639935"},
+		{value: "0.0000001", decimals: 6, want: "0", wantTruncated: true},
+		{value: "1e-19", decimals: 18, want: "0", wantTruncated: true},
+		{value: "1.23456789", decimals: 6, want: "1234567", wantTruncated: true},
+		{value: "1.2345670", decimals: 6, want: "1234567"},
+		{value: "-1", decimals: 18, wantErr: true},
+		{value: "1.2.3", decimals: 18, wantErr: true},
+		{value: "abc", decimals: 18, wantErr: true},
+		{value: "", decimals: 18, wantErr: true},
+		{value: "1e", decimals: 18, wantErr: true},
+		{value: "1e100", decimals: 0, want: "1" + strings.Repeat("0", 100)},
+		{value: "1e1000000000", decimals: 18, wantErr: true},
+		{value: "1e-1000000000", decimals: 18, wantErr: true},
+		{value: "1e9223372036854775807", decimals: 18, wantErr: true},
+		{value: "1", decimals: 256, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, truncated, err := parseDecimalAmount(tt.value, tt.decimals)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseDecimalAmount(%q, %d) expected error, got %s", tt.value, tt.decimals, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseDecimalAmount(%q, %d) unexpected error: %v", tt.value, tt.decimals, err)
+			continue
+		}
+		if got.String() != tt.want || truncated != tt.wantTruncated {
+			t.Errorf("parseDecimalAmount(%q, %d) = %s, %v, want %s, %v", tt.value, tt.decimals, got, truncated, tt.want, tt.wantTruncated)
+		}
+	}
+}
+
+func TestProcessActivityUsesRawValue(t *testing.T) {
+	tests := []struct {
+		name        string
+		activity    string
+		want        string
+		wantWarning bool
+	}{
+		{
+			name: "external with raw value",
+			activity: `{"blockNum":"0x10","hash":"0x` + testHash + `","fromAddress":"0x` + testAddrA + `","toAddress":"0x` + testAddrB + `",
+				"value":12345678.123456789,"category":"external","rawContract":{"rawValue":"0xa364c8d5745e591485f15","decimals":18}}`,
+			want: "12345678123456789123456789",
+		},
+		{
+			name: "external without raw value",
+			activity: `{"blockNum":"0x10","hash":"0x` + testHash + `","fromAddress":"0x` + testAddrA + `","toAddress":"0x` + testAddrB + `",
+				"value":0.123456789123456789,"category":"external"}`,
+			want: "123456789123456789",
+		},
+		{
+			name: "erc20 without raw value",
+			activity: `{"blockNum":"0x10","hash":"0x` + testHash + `","fromAddress":"0x` + testAddrA + `","toAddress":"0x` + testAddrB + `",
+				"value":1000000.000001,"category":"erc20","asset":"USDC","rawContract":{"address":"0x` + testAddrC + `","decimals":6}}`,
+			want: "1000000000001",
+		},
+		{
+			name: "erc20 without raw value beyond decimals",
+			activity: `{"blockNum":"0x10","hash":"0x` + testHash + `","fromAddress":"0x` + testAddrA + `","toAddress":"0x` + testAddrB + `",
+				"value":1.23456789,"category":"erc20","asset":"USDC","rawContract":{"address":"0x` + testAddrC + `","decimals":6}}`,
+			want:        "1234567",
+			wantWarning: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var activity AlchemyActivity
+			if err := json.Unmarshal([]byte(tt.activity), &activity); err != nil {
+				t.Fatalf("failed to parse activity: %v", err)
+			}
+
+			var got string
+			var logs bytes.Buffer
+			processor := NewProcessor(zerolog.New(&logs), nil, map[string]string{}, func(ctx context.Context, a ProcessedActivity) error {
+				got = a.Value
+				return nil
+			}, "eth-mainnet")
+
+			if err := processor.ProcessActivity(context.Background(), activity); err != nil {
+				t.Fatalf("ProcessActivity returned error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("value = %s, want %s", got, tt.want)
+			}
+			if warned := strings.Contains(logs.String(), "truncating"); warned != tt.wantWarning {
+				t.Errorf("truncation warning logged = %v, want %v", warned, tt.wantWarning)
+			}
+		})
+	}
+}
+
+const (
+	testHash  = "88df016429689c079f3b2f6ad39fa052532c56795b733da78a91ebe6a713944b"
+	testAddrA = "71660c4005ba85c37ccec55d0c4493e66fe775d3"
+	testAddrB = "0d4a11d5eeaac28ec3f61d100daf4d40471f1852"
+	testAddrC = "a0b86991c6218b36c1d19d4a2e9eb0ce3606eb48"
+)
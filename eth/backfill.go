@@ -5,24 +5,119 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
-	"sync/atomic"
+	"sync"
 	"time"
 
+	"github.com/dawitel/alchemy-webhook/backfill"
 	"github.com/dawitel/alchemy-webhook/cache"
+	"github.com/dawitel/alchemy-webhook/checkpoint"
+	"github.com/dawitel/alchemy-webhook/ratelimit"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/rs/zerolog"
 )
 
+const (
+	defaultBackfillTimeRange = 12 * time.Hour
+	// defaultConfirmationDepth is the number of most recent blocks left out
+	// of a backfill because they may still be reorganized
+	defaultConfirmationDepth = 12
+	// defaultAddressBatchSize is the number of addresses per alchemy_getAssetTransfers call
+	defaultAddressBatchSize = 100
+	defaultBackfillWorkers  = 4
+	// defaultRequestsPerSecond limits alchemy_getAssetTransfers calls across all workers
+	defaultRequestsPerSecond = 5
+	// maxTransfersPerPage is the alchemy_getAssetTransfers page size
+	maxTransfersPerPage = 1000
+)
+
 // Backfill handles Ethereum historical transaction backfill
 type Backfill struct {
-	rpcClient   *ethclient.Client
-	processor   *Processor
-	logger      zerolog.Logger
-	cache       cache.Cache
-	timeRange   time.Duration
-	batchSize   int
-	backfilling int32
+	rpcClient         *ethclient.Client
+	processor         *Processor
+	logger            zerolog.Logger
+	cache             cache.Cache
+	timeRange         time.Duration
+	batchSize         int
+	confirmationDepth uint64
+	workers           int
+	direction         BackfillDirection
+	maxPages          int
+	checkpoints       checkpoint.Store
+	limiter           *ratelimit.Limiter
+	blockTimes        *BlockTimes
+	runner            backfill.Runner
+}
+
+// BackfillOption configures optional Backfill behaviour
+type BackfillOption func(*Backfill)
+
+// WithConfirmationDepth sets how many of the most recent blocks are left out
+// of a backfill. The default is 12.
+func WithConfirmationDepth(blocks uint64) BackfillOption {
+	return func(b *Backfill) {
+		b.confirmationDepth = blocks
+	}
+}
+
+// BackfillDirection selects which transfers of the watched addresses are backfilled
+type BackfillDirection string
+
+// Backfill directions
+const (
+	BackfillIncoming BackfillDirection = "incoming" // Transfers to the watched addresses
+	BackfillOutgoing BackfillDirection = "outgoing" // Transfers from the watched addresses
+	BackfillBoth     BackfillDirection = "both"     // Transfers to and from the watched addresses
+)
+
+// ParseBackfillDirection parses a backfill direction name. An empty name is incoming.
+func ParseBackfillDirection(name string) (BackfillDirection, error) {
+	switch BackfillDirection(strings.ToLower(strings.TrimSpace(name))) {
+	case "", BackfillIncoming:
+		return BackfillIncoming, nil
+	case BackfillOutgoing:
+		return BackfillOutgoing, nil
+	case BackfillBoth:
+		return BackfillBoth, nil
+	default:
+		return "", fmt.Errorf("invalid backfill direction: %s (must be 'incoming', 'outgoing' or 'both')", name)
+	}
+}
+
+// WithDirection sets which transfers are backfilled. The default is incoming.
+func WithDirection(direction BackfillDirection) BackfillOption {
+	return func(b *Backfill) {
+		b.direction = direction
+	}
+}
+
+// WithPageLimit stops reading the transfers of an address batch after
+// maxPages pages of up to 1000 transfers. The batch is then reported in
+// BackfillResult.Truncated. The default, 0, reads every page.
+func WithPageLimit(maxPages int) BackfillOption {
+	return func(b *Backfill) {
+		b.maxPages = maxPages
+	}
+}
+
+// WithWorkers sets how many address batches are fetched and processed
+// concurrently. The default is 4.
+func WithWorkers(workers int) BackfillOption {
+	return func(b *Backfill) {
+		if workers > 0 {
+			b.workers = workers
+		}
+	}
+}
+
+// WithRateLimit limits alchemy_getAssetTransfers calls, pages included, to
+// perSecond on average with bursts of up to burst calls. A perSecond <= 0
+// disables the limit. The default is 5 calls per second.
+func WithRateLimit(perSecond float64, burst int) BackfillOption {
+	return func(b *Backfill) {
+		b.limiter = ratelimit.New(perSecond, burst)
+	}
 }
 
 // NewBackfill creates a new Ethereum backfill instance
@@ -33,173 +128,533 @@ func NewBackfill(
 	cache cache.Cache,
 	timeRange time.Duration,
 	batchSize int,
+	opts ...BackfillOption,
 ) *Backfill {
-	return &Backfill{
-		rpcClient: rpcClient,
-		processor: processor,
-		logger:    logger,
-		cache:     cache,
-		timeRange: timeRange,
-		batchSize: batchSize,
+	if timeRange <= 0 {
+		timeRange = defaultBackfillTimeRange
+	}
+
+	b := &Backfill{
+		rpcClient:         rpcClient,
+		processor:         processor,
+		logger:            logger,
+		cache:             cache,
+		timeRange:         timeRange,
+		batchSize:         batchSize,
+		confirmationDepth: defaultConfirmationDepth,
+		workers:           defaultBackfillWorkers,
+		direction:         BackfillIncoming,
+		limiter:           ratelimit.New(defaultRequestsPerSecond, defaultRequestsPerSecond),
+		blockTimes:        NewBlockTimes(rpcClient, 0, logger),
 	}
-}
 
-// Backfill performs backfill for the given addresses
-func (b *Backfill) Backfill(ctx context.Context, addresses []string) error {
-	if !atomic.CompareAndSwapInt32(&b.backfilling, 0, 1) {
-		b.logger.Debug().Msg("Backfill already in progress, skipping")
-		return nil
+	for _, opt := range opts {
+		opt(b)
 	}
-	defer atomic.StoreInt32(&b.backfilling, 0)
 
+	return b
+}
+
+// Backfill starts a backfill of the given addresses over the configured
+// time range, up to the confirmation depth below the chain head, and returns
+// its job. While another backfill runs, it returns that job with
+// backfill.ErrInProgress.
+func (b *Backfill) Backfill(ctx context.Context, addresses []string) (*backfill.Job, error) {
+	return b.runner.Start(ctx, func(ctx context.Context, job *backfill.Job) error {
+		_, err := b.run(ctx, job, addresses)
+		return err
+	})
+}
+
+// Job returns the last backfill job started, nil if none
+func (b *Backfill) Job() *backfill.Job {
+	return b.runner.Current()
+}
+
+// BackfillResult summarizes a backfill run
+type BackfillResult struct {
+	FromBlock uint64
+	ToBlock   uint64
+	Processed int
+	Skipped   int
+	Failed    int
+	// Truncated lists the queries that stopped at the page limit. Each can be
+	// continued with WithBlockRange(query.FromBlock, ToBlock).
+	Truncated []TruncatedQuery
+}
+
+// TruncatedQuery is an alchemy_getAssetTransfers query that was not read to
+// the end. Page keys are tied to the exact query, so the query reports the
+// first block it did not read completely instead.
+type TruncatedQuery struct {
+	Addresses []string
+	Direction BackfillDirection // BackfillIncoming or BackfillOutgoing
+	FromBlock uint64            // First block not read completely
+}
+
+// BackfillRange performs backfill for the given addresses. Without options
+// it covers the configured time range; WithBlockRange or WithTimeRange
+// select an explicit range instead. It waits for the backfill to end and
+// returns backfill.ErrInProgress while another backfill runs.
+func (b *Backfill) BackfillRange(ctx context.Context, addresses []string, opts ...RangeOption) (*BackfillResult, error) {
+	var result *BackfillResult
+	_, err := b.runner.Run(ctx, func(ctx context.Context, job *backfill.Job) error {
+		var err error
+		result, err = b.run(ctx, job, addresses, opts...)
+		return err
+	})
+	return result, err
+}
+
+// run performs a backfill, reporting its progress to job
+func (b *Backfill) run(ctx context.Context, job *backfill.Job, addresses []string, opts ...RangeOption) (*BackfillResult, error) {
 	if b.rpcClient == nil {
-		return fmt.Errorf("RPC client not available")
+		return nil, fmt.Errorf("RPC client not available")
 	}
 
 	if len(addresses) == 0 {
 		b.logger.Debug().Msg("No addresses to backfill")
-		return nil
+		return &BackfillResult{}, nil
 	}
 
 	b.logger.Info().
 		Int("address_count", len(addresses)).
 		Dur("time_range", b.timeRange).
+		Str("direction", string(b.direction)).
 		Msg("Starting Ethereum historical deposit backfill")
 
-	currentBlock, err := b.rpcClient.BlockNumber(ctx)
+	fromBlock, toBlock, err := b.resolveRange(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve backfill range: %w", err)
+	}
+
+	result := &BackfillResult{FromBlock: fromBlock, ToBlock: toBlock}
+	if fromBlock > toBlock {
+		b.logger.Debug().
+			Uint64("from_block", fromBlock).
+			Uint64("to_block", toBlock).
+			Msg("Block range empty, skipping backfill")
+		return result, nil
+	}
+
+	b.logger.Info().
+		Uint64("from_block", fromBlock).
+		Uint64("to_block", toBlock).
+		Msg("Backfilling historical deposits")
+
+	units, err := b.planUnits(ctx, addresses, fromBlock, toBlock, len(opts) == 0)
 	if err != nil {
-		return fmt.Errorf("failed to get current block number: %w", err)
+		return nil, err
 	}
 
-	const blocksPer12Hours = 3600
-	const safetyMargin = 400
-	const confirmationBlocks = 12
+	// An address is done once every query including it has ended
+	pending := make(map[common.Address]int)
+	for _, unit := range units {
+		for _, addr := range unit.addresses {
+			pending[addr]++
+		}
+	}
+	total := len(dedupeAddresses(addresses))
+	job.SetAddresses(total)
+	job.CompleteAddresses(total - len(pending))
+
+	work := make(chan backfillUnit)
+	go func() {
+		defer close(work)
+		for _, unit := range units {
+			select {
+			case work <- unit:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
 
-	fromBlock := uint64(0)
-	if currentBlock > blocksPer12Hours+safetyMargin+confirmationBlocks {
-		fromBlock = currentBlock - blocksPer12Hours - safetyMargin
+	var seen *transferSet
+	if b.direction == BackfillBoth {
+		seen = newTransferSet()
 	}
-	toBlock := currentBlock - confirmationBlocks
 
-	if fromBlock >= toBlock {
-		b.logger.Debug().
-			Uint64("current_block", currentBlock).
-			Msg("Block range too small for backfill, skipping")
-		return nil
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for range b.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for unit := range work {
+				unitResult := b.backfillUnit(ctx, job, unit, toBlock, seen)
+
+				mu.Lock()
+				result.Processed += unitResult.Processed
+				result.Skipped += unitResult.Skipped
+				result.Failed += unitResult.Failed
+				result.Truncated = append(result.Truncated, unitResult.Truncated...)
+				done := 0
+				for _, addr := range unit.addresses {
+					pending[addr]--
+					if pending[addr] == 0 {
+						done++
+					}
+				}
+				mu.Unlock()
+
+				job.CompleteAddresses(done)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+
+	for _, query := range result.Truncated {
+		b.logger.Warn().
+			Str("first_address", query.Addresses[0]).
+			Int("address_count", len(query.Addresses)).
+			Str("direction", string(query.Direction)).
+			Uint64("continue_from_block", query.FromBlock).
+			Msg("Asset transfers truncated at page limit")
 	}
 
 	b.logger.Info().
+		Int("processed", result.Processed).
+		Int("skipped", result.Skipped).
+		Int("failed", result.Failed).
+		Int("truncated", len(result.Truncated)).
 		Uint64("from_block", fromBlock).
 		Uint64("to_block", toBlock).
-		Uint64("current_block", currentBlock).
-		Msg("Backfilling historical deposits")
+		Msg("Ethereum historical deposit backfill completed")
+
+	return result, nil
+}
+
+// backfillUnit is one alchemy_getAssetTransfers query: a batch of addresses
+// in one direction, starting at the same block
+type backfillUnit struct {
+	direction BackfillDirection // BackfillIncoming or BackfillOutgoing
+	fromBlock uint64
+	addresses []common.Address
+	resume    bool // Whether checkpoints are read and written
+}
+
+// planUnits splits addresses into queries per direction. With resume set,
+// addresses start after their checkpoint instead of at fromBlock and are
+// grouped by start block; addresses already backfilled up to toBlock are left
+// out.
+func (b *Backfill) planUnits(ctx context.Context, addresses []string, fromBlock, toBlock uint64, resume bool) ([]backfillUnit, error) {
+	addressList := dedupeAddresses(addresses)
+	resume = resume && b.checkpoints != nil
+
+	var directions []BackfillDirection
+	if b.direction != BackfillOutgoing {
+		directions = append(directions, BackfillIncoming)
+	}
+	if b.direction != BackfillIncoming {
+		directions = append(directions, BackfillOutgoing)
+	}
+
+	var units []backfillUnit
+	for _, direction := range directions {
+		var starts []uint64
+		byStart := make(map[uint64][]common.Address)
+		for _, addr := range addressList {
+			start := fromBlock
+			if resume {
+				cp, ok, err := b.checkpoints.Get(ctx, b.checkpointKey(direction, addr))
+				if err != nil {
+					return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+				}
+				if ok {
+					start = cp.Height + 1
+				}
+			}
+			if start > toBlock {
+				continue
+			}
+			if _, ok := byStart[start]; !ok {
+				starts = append(starts, start)
+			}
+			byStart[start] = append(byStart[start], addr)
+		}
+
+		for _, start := range starts {
+			group := byStart[start]
+			for i := 0; i < len(group); i += b.addressBatchSize() {
+				units = append(units, backfillUnit{
+					direction: direction,
+					fromBlock: start,
+					addresses: group[i:min(i+b.addressBatchSize(), len(group))],
+					resume:    resume,
+				})
+			}
+		}
+	}
 
-	processedCount := 0
-	skippedCount := 0
+	return units, nil
+}
 
+// dedupeAddresses parses addresses and drops duplicates, keeping their order
+func dedupeAddresses(addresses []string) []common.Address {
+	seen := make(map[common.Address]struct{}, len(addresses))
 	addressList := make([]common.Address, 0, len(addresses))
 	for _, addrStr := range addresses {
 		addr := common.HexToAddress(addrStr)
+		if _, ok := seen[addr]; ok {
+			continue
+		}
+		seen[addr] = struct{}{}
 		addressList = append(addressList, addr)
 	}
+	return addressList
+}
+
+// backfillUnit streams and processes the transfers of one query up to
+// toBlock, reporting counts to job as pages are processed. When seen is set,
+// transfers it already holds are skipped. With checkpoints, the last fully
+// processed block is recorded after every page until a transfer fails to
+// process.
+func (b *Backfill) backfillUnit(ctx context.Context, job *backfill.Job, unit backfillUnit, toBlock uint64, seen *transferSet) BackfillResult {
+	var result BackfillResult
+
+	watched := make(map[common.Address]struct{}, len(unit.addresses))
+	for _, addr := range unit.addresses {
+		watched[addr] = struct{}{}
+	}
+
+	toAddresses, fromAddresses := unit.addresses, []common.Address(nil)
+	if unit.direction == BackfillOutgoing {
+		toAddresses, fromAddresses = nil, unit.addresses
+	}
 
-	for _, addr := range addressList {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
+	next := unit.fromBlock // First block not read completely
+	stalled := false
+	pageKey, err := b.getAssetTransfers(ctx, unit.fromBlock, toBlock, toAddresses, fromAddresses, func(page []AlchemyAssetTransfer, more bool) {
+		if seen != nil {
+			page = seen.unique(page)
+		}
+		processed, skipped, failed := b.processTransfers(ctx, page, watched)
+		result.Processed += processed
+		result.Skipped += skipped
+		result.Failed += failed
+		job.Count(processed, skipped, failed)
+
+		if more {
+			// The last block of the page may continue on the next page
+			if last, ok := lastBlock(page); ok && last > next {
+				next = last
+			}
+		} else {
+			next = toBlock + 1
 		}
 
-		transfers, err := b.getAssetTransfers(ctx, fromBlock, toBlock, []common.Address{addr}, nil)
+		if !unit.resume || stalled {
+			return
+		}
+		if failed > 0 || ctx.Err() != nil {
+			stalled = true
+			return
+		}
+		if next > unit.fromBlock {
+			b.saveCheckpoints(ctx, unit, next-1)
+		}
+	})
+	if err != nil {
+		b.logger.Warn().
+			Err(err).
+			Str("first_address", unit.addresses[0].Hex()).
+			Int("address_count", len(unit.addresses)).
+			Str("direction", string(unit.direction)).
+			Msg("Failed to get asset transfers, skipping address batch")
+		for _, addr := range unit.addresses {
+			job.AddressError(addr.Hex(), fmt.Errorf("%s transfers: %w", unit.direction, err))
+		}
+	}
+	// A failed query is reported through its address errors only
+	if err == nil && pageKey != "" {
+		addresses := make([]string, len(unit.addresses))
+		for i, addr := range unit.addresses {
+			addresses[i] = addr.Hex()
+		}
+		result.Truncated = append(result.Truncated, TruncatedQuery{
+			Addresses: addresses,
+			Direction: unit.direction,
+			FromBlock: next,
+		})
+		for _, addr := range addresses {
+			job.AddressError(addr, fmt.Errorf("%s transfers truncated at page limit, continue from block %d", unit.direction, next))
+		}
+	}
+
+	return result
+}
+
+// transferSet is a concurrency-safe set of transfer unique IDs
+type transferSet struct {
+	mu  sync.Mutex
+	ids map[string]struct{}
+}
+
+// newTransferSet creates an empty transfer set
+func newTransferSet() *transferSet {
+	return &transferSet{ids: make(map[string]struct{})}
+}
+
+// unique returns the transfers not in the set yet and adds them
+func (s *transferSet) unique(transfers []AlchemyAssetTransfer) []AlchemyAssetTransfer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unique := transfers[:0:0]
+	for _, transfer := range transfers {
+		id := transfer.UniqueID
+		if id == "" {
+			id = normalizeTxHash(transfer.Hash) + ":" + strings.ToLower(transfer.Category)
+		}
+		if _, ok := s.ids[id]; ok {
+			continue
+		}
+		s.ids[id] = struct{}{}
+		unique = append(unique, transfer)
+	}
+	return unique
+}
+
+// lastBlock returns the highest block number of a page of transfers
+func lastBlock(transfers []AlchemyAssetTransfer) (uint64, bool) {
+	var last uint64
+	found := false
+	for _, transfer := range transfers {
+		number, err := hexutil.DecodeUint64(transfer.BlockNum)
 		if err != nil {
-			b.logger.Warn().
-				Err(err).
-				Str("address", addr.Hex()).
-				Msg("Failed to get asset transfers, skipping address")
-			time.Sleep(2 * time.Second)
 			continue
 		}
+		if !found || number > last {
+			last = number
+			found = true
+		}
+	}
+	return last, found
+}
 
-		for _, transfer := range transfers {
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			default:
-			}
+// processTransfers processes a page of transfers of watched addresses and
+// returns how many were processed, skipped as duplicates and failed.
+// Transfers that neither come from nor go to a watched address are skipped.
+func (b *Backfill) processTransfers(ctx context.Context, transfers []AlchemyAssetTransfer, watched map[common.Address]struct{}) (int, int, int) {
+	var page []AlchemyAssetTransfer
+	for _, transfer := range transfers {
+		_, toWatched := watched[common.HexToAddress(transfer.To)]
+		_, fromWatched := watched[common.HexToAddress(transfer.From)]
+		if !toWatched && !fromWatched {
+			b.logger.Debug().
+				Str("tx_hash", transfer.Hash).
+				Str("from", transfer.From).
+				Str("to", transfer.To).
+				Msg("Transfer does not match a watched address, skipping")
+			continue
+		}
+		page = append(page, transfer)
+	}
 
-			txHash := strings.ToLower(strings.TrimPrefix(transfer.Hash, "0x"))
-			if !strings.HasPrefix(txHash, "0x") {
-				txHash = "0x" + txHash
-			}
+	activities := make([]AlchemyActivity, len(page))
+	for i, transfer := range page {
+		activities[i] = transferToActivity(transfer)
+	}
+	numberInternalTransfers(activities)
 
-			if b.cache != nil {
-				processed, err := b.cache.IsProcessed(ctx, txHash)
-				if err == nil && processed {
-					skippedCount++
-					continue
-				}
-			}
+	var eventIDs []string
+	offsets := make([]int, len(page)+1)
+	for i, activity := range activities {
+		eventIDs = append(eventIDs, activityEventIDs(activity)...)
+		offsets[i+1] = len(eventIDs)
+	}
+	processed := b.checkProcessed(ctx, eventIDs)
 
-			if err := b.processHistoricalTransfer(ctx, transfer, addr); err != nil {
-				b.logger.Warn().
-					Err(err).
-					Str("tx_hash", txHash).
-					Msg("Failed to process historical transfer")
-				continue
-			}
+	processedCount, skippedCount, failedCount := 0, 0, 0
+	for i, transfer := range page {
+		if ctx.Err() != nil {
+			break
+		}
 
-			processedCount++
+		if allProcessed(processed[offsets[i]:offsets[i+1]]) {
+			skippedCount++
+			continue
 		}
 
-		time.Sleep(1 * time.Second)
+		if err := b.processor.ProcessActivity(ctx, activities[i]); err != nil {
+			b.logger.Warn().
+				Err(err).
+				Str("tx_hash", transfer.Hash).
+				Msg("Failed to process historical transfer")
+			failedCount++
+			continue
+		}
+
+		processedCount++
 	}
 
-	b.logger.Info().
-		Int("processed", processedCount).
-		Int("skipped", skippedCount).
-		Uint64("from_block", fromBlock).
-		Uint64("to_block", toBlock).
-		Msg("Ethereum historical deposit backfill completed")
+	return processedCount, skippedCount, failedCount
+}
 
-	return nil
+// addressBatchSize returns how many addresses are queried per alchemy_getAssetTransfers call
+func (b *Backfill) addressBatchSize() int {
+	if b.batchSize <= 0 {
+		return defaultAddressBatchSize
+	}
+	return b.batchSize
 }
 
-// getAssetTransfers fetches asset transfers using alchemy_getAssetTransfers
-func (b *Backfill) getAssetTransfers(ctx context.Context, fromBlock, toBlock uint64, toAddresses, fromAddresses []common.Address) ([]AlchemyAssetTransfer, error) {
-	if b.rpcClient == nil {
-		return nil, fmt.Errorf("RPC client not initialized")
+// checkProcessed looks up a page of dedupe keys in one batch call.
+// On cache errors every key is reported as unprocessed.
+func (b *Backfill) checkProcessed(ctx context.Context, keys []string) []bool {
+	if b.cache == nil {
+		return make([]bool, len(keys))
 	}
 
-	var result struct {
-		Transfers []AlchemyAssetTransfer `json:"transfers"`
-		PageKey   string                 `json:"pageKey"`
+	processed, err := cache.AsBatch(b.cache).IsProcessedBatch(ctx, keys)
+	if err != nil {
+		b.logger.Warn().
+			Err(err).
+			Int("key_count", len(keys)).
+			Msg("Failed to check processed transfers, continuing")
+		return make([]bool, len(keys))
 	}
+	return processed
+}
 
-	allTransfers := []AlchemyAssetTransfer{}
+// normalizeTxHash lowercases a transaction hash and ensures the 0x prefix
+func normalizeTxHash(hash string) string {
+	txHash := strings.ToLower(strings.TrimPrefix(hash, "0x"))
+	return "0x" + txHash
+}
 
-	toAddressStrs := make([]string, len(toAddresses))
-	for i, addr := range toAddresses {
-		toAddressStrs[i] = addr.Hex()
+// getAssetTransfers streams asset transfers using alchemy_getAssetTransfers,
+// passing each page to handle as it arrives together with whether more pages
+// follow. Pages are in ascending block order. It returns the key of the next
+// page when it stops at the page limit, and an empty key when every page was
+// read.
+func (b *Backfill) getAssetTransfers(ctx context.Context, fromBlock, toBlock uint64, toAddresses, fromAddresses []common.Address, handle func(page []AlchemyAssetTransfer, more bool)) (string, error) {
+	if b.rpcClient == nil {
+		return "", fmt.Errorf("RPC client not initialized")
 	}
 
 	blockRange := toBlock - fromBlock + 1
-	maxCount := "0x3e8"
-	if blockRange > 100 {
-		maxCount = "0x3e8"
-	} else if blockRange > 10 {
-		maxCount = "0x3e8"
-	} else {
-		maxCount = "0x1f4"
-	}
-
 	params := map[string]interface{}{
 		"fromBlock":        fmt.Sprintf("0x%x", fromBlock),
 		"toBlock":          fmt.Sprintf("0x%x", toBlock),
-		"toAddress":        toAddressStrs,
 		"category":         []string{"external", "internal", "erc20", "erc721", "erc1155"},
-		"withMetadata":     false,
+		"withMetadata":     true,
+		"order":            "asc",
 		"excludeZeroValue": blockRange > 10,
-		"maxCount":         maxCount,
+		"maxCount":         hexutil.EncodeUint64(maxTransfersPerPage),
+	}
+
+	if len(toAddresses) > 0 {
+		toAddressStrs := make([]string, len(toAddresses))
+		for i, addr := range toAddresses {
+			toAddressStrs[i] = addr.Hex()
+		}
+		params["toAddress"] = toAddressStrs
 	}
 
 	if len(fromAddresses) > 0 {
@@ -211,77 +666,63 @@ func (b *Backfill) getAssetTransfers(ctx context.Context, fromBlock, toBlock uin
 	}
 
 	pageKey := ""
-	maxPaginationIterations := 100
-	maxTotalTransfers := 10000
-	iterationCount := 0
-
-	for iterationCount < maxPaginationIterations {
-		select {
-		case <-ctx.Done():
-			return allTransfers, ctx.Err()
-		default:
-		}
-
-		if len(allTransfers) >= maxTotalTransfers {
-			b.logger.Warn().
-				Int("total_transfers", len(allTransfers)).
-				Msg("Reached maximum transfers limit, stopping pagination")
-			break
+	for pages := 0; ; pages++ {
+		if b.maxPages > 0 && pages >= b.maxPages {
+			return pageKey, nil
 		}
 
 		if pageKey != "" {
 			params["pageKey"] = pageKey
 		}
 
-		err := b.rpcClient.Client().CallContext(ctx, &result, "alchemy_getAssetTransfers", params)
-		if err != nil {
-			return allTransfers, fmt.Errorf("alchemy_getAssetTransfers failed: %w", err)
+		if err := b.limiter.Wait(ctx); err != nil {
+			return pageKey, err
 		}
 
-		allTransfers = append(allTransfers, result.Transfers...)
-		iterationCount++
+		var result AlchemyAssetTransfersResponse
+		if err := b.rpcClient.Client().CallContext(ctx, &result, "alchemy_getAssetTransfers", params); err != nil {
+			return pageKey, fmt.Errorf("alchemy_getAssetTransfers failed: %w", err)
+		}
+
+		handle(result.Transfers, result.PageKey != "")
 
 		if result.PageKey == "" {
-			break
+			return "", nil
 		}
-
-		if len(result.Transfers) == 0 && result.PageKey != "" {
-			b.logger.Warn().
-				Str("page_key", result.PageKey).
-				Msg("Received empty transfers but pageKey exists, stopping pagination")
-			break
+		if result.PageKey == pageKey {
+			return pageKey, fmt.Errorf("alchemy_getAssetTransfers returned the same page key twice")
 		}
-
 		pageKey = result.PageKey
 	}
-
-	return allTransfers, nil
 }
 
-// processHistoricalTransfer processes a historical transfer
-func (b *Backfill) processHistoricalTransfer(ctx context.Context, transfer AlchemyAssetTransfer, toAddress common.Address) error {
-	toAddrStr := strings.ToLower(toAddress.Hex())
-	if !strings.HasPrefix(toAddrStr, "0x") {
-		toAddrStr = "0x" + toAddrStr
-	}
-
-	fromAddrStr := strings.ToLower(strings.TrimPrefix(transfer.From, "0x"))
-	if !strings.HasPrefix(fromAddrStr, "0x") {
-		fromAddrStr = "0x" + fromAddrStr
+// transferToActivity converts an asset transfer into the webhook activity
+// format. Addresses are left as they are; the processor applies its address
+// format to webhook and backfill activities alike.
+func transferToActivity(transfer AlchemyAssetTransfer) AlchemyActivity {
+	activity := AlchemyActivity{
+		BlockNum:        transfer.BlockNum,
+		Hash:            transfer.Hash,
+		FromAddress:     transfer.From,
+		ToAddress:       transfer.To,
+		Value:           transfer.Value,
+		ERC721TokenID:   transfer.ERC721TokenID,
+		ERC1155Metadata: transfer.ERC1155Metadata,
+		Asset:           transfer.Asset,
+		Category:        transfer.Category,
+		Metadata:        transfer.Metadata,
 	}
 
-	// Convert to AlchemyActivity format
-	activity := AlchemyActivity{
-		BlockNum:    transfer.BlockNum,
-		Hash:        transfer.Hash,
-		FromAddress: fromAddrStr,
-		ToAddress:   toAddrStr,
-		Value:       transfer.Value,
-		Asset:       transfer.Asset,
-		Category:    transfer.Category,
+	// Alchemy unique IDs of log-based transfers have the form "hash:log:index"
+	if parts := strings.Split(transfer.UniqueID, ":"); len(parts) == 3 && parts[1] == "log" {
+		activity.Log = &AlchemyLog{
+			TransactionHash: transfer.Hash,
+			BlockNumber:     transfer.BlockNum,
+			LogIndex:        parts[2],
+		}
 	}
 
-	if transfer.RawContract.Address != "" {
+	if transfer.RawContract.Address != "" || transfer.RawContract.Value != "" {
 		activity.RawContract = &AlchemyRawContract{
 			RawValue: transfer.RawContract.Value,
 			Address:  transfer.RawContract.Address,
@@ -296,6 +737,35 @@ func (b *Backfill) processHistoricalTransfer(ctx context.Context, transfer Alche
 		}
 	}
 
-	// Process using the processor
-	return b.processor.ProcessActivity(ctx, activity)
+	return activity
+}
+
+// activityEventIDs returns the dedupe keys the processor uses for an
+// activity; ERC-1155 transfers have one key per token ID
+func activityEventIDs(activity AlchemyActivity) []string {
+	category := strings.ToLower(activity.Category)
+	eventID := activityEventID(normalizeTxHash(activity.Hash), category, activity)
+
+	if category != "erc1155" || len(activity.ERC1155Metadata) == 0 {
+		return []string{eventID}
+	}
+
+	eventIDs := make([]string, len(activity.ERC1155Metadata))
+	for i, metadata := range activity.ERC1155Metadata {
+		eventIDs[i] = erc1155EventID(eventID, i, metadata.TokenID)
+	}
+	return eventIDs
+}
+
+// allProcessed reports whether every key of a transfer was already processed
+func allProcessed(processed []bool) bool {
+	if len(processed) == 0 {
+		return false
+	}
+	for _, p := range processed {
+		if !p {
+			return false
+		}
+	}
+	return true
 }
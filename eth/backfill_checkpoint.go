@@ -0,0 +1,46 @@
+package eth
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/dawitel/alchemy-webhook/checkpoint"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// WithCheckpoints makes backfill resumable. Runs without an explicit range
+// record the last fully backfilled block per address and direction, and the
+// next such run starts each address after its checkpoint instead of at the
+// start of the time range, so a scheduled backfill covers only new blocks.
+func WithCheckpoints(store checkpoint.Store) BackfillOption {
+	return func(b *Backfill) {
+		b.checkpoints = store
+	}
+}
+
+// checkpointKey returns the checkpoint key of an address in one direction
+func (b *Backfill) checkpointKey(direction BackfillDirection, addr common.Address) string {
+	chain := "eth"
+	if b.processor != nil && b.processor.chainID != "" {
+		chain = b.processor.chainID
+	}
+	return checkpoint.Key(chain, string(direction), strings.ToLower(addr.Hex()))
+}
+
+// saveCheckpoints records height as backfilled for every address of unit
+// in one write
+func (b *Backfill) saveCheckpoints(ctx context.Context, unit backfillUnit, height uint64) {
+	now := time.Now()
+	checkpoints := make([]checkpoint.Checkpoint, len(unit.addresses))
+	for i, addr := range unit.addresses {
+		checkpoints[i] = checkpoint.Checkpoint{Key: b.checkpointKey(unit.direction, addr), Height: height, UpdatedAt: now}
+	}
+	if err := b.checkpoints.PutMany(ctx, checkpoints); err != nil {
+		b.logger.Warn().
+			Err(err).
+			Str("first_key", checkpoints[0].Key).
+			Int("address_count", len(checkpoints)).
+			Msg("Failed to save backfill checkpoints")
+	}
+}
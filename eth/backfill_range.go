@@ -0,0 +1,108 @@
+package eth
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// RangeOption selects the block range of a single backfill run
+type RangeOption func(*backfillRange)
+
+// backfillRange is an explicit block or time range requested by the caller
+type backfillRange struct {
+	fromBlock, toBlock *uint64
+	start, end         time.Time
+}
+
+// WithBlockRange backfills blocks from through to, inclusive. A to of zero
+// means up to the confirmation depth below the head; larger values are
+// capped there as well.
+func WithBlockRange(from, to uint64) RangeOption {
+	return func(r *backfillRange) {
+		r.fromBlock = &from
+		r.toBlock = &to
+	}
+}
+
+// WithTimeRange backfills the blocks produced between start and end,
+// inclusive. A zero end means up to the confirmation depth below the head.
+func WithTimeRange(start, end time.Time) RangeOption {
+	return func(r *backfillRange) {
+		r.start = start
+		r.end = end
+	}
+}
+
+// resolveRange returns the first and last block to backfill. Time bounds are
+// mapped to blocks by binary search on block timestamps. The result is empty
+// when from > to.
+func (b *Backfill) resolveRange(ctx context.Context, opts ...RangeOption) (uint64, uint64, error) {
+	var r backfillRange
+	for _, opt := range opts {
+		opt(&r)
+	}
+
+	head, err := b.rpcClient.BlockNumber(ctx)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get current block number: %w", err)
+	}
+	if head < b.confirmationDepth {
+		return 1, 0, nil
+	}
+	safeHead := head - b.confirmationDepth
+
+	if r.fromBlock != nil {
+		to := *r.toBlock
+		if to == 0 || to > safeHead {
+			to = safeHead
+		}
+		return *r.fromBlock, to, nil
+	}
+
+	start := r.start
+	if start.IsZero() {
+		start = time.Now().Add(-b.timeRange)
+	}
+
+	from, err := b.firstBlockAtOrAfter(ctx, start, safeHead)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	to := safeHead
+	if !r.end.IsZero() {
+		next, err := b.firstBlockAtOrAfter(ctx, r.end.Add(time.Second), safeHead)
+		if err != nil {
+			return 0, 0, err
+		}
+		if next == 0 {
+			return 1, 0, nil
+		}
+		to = next - 1
+	}
+
+	return from, to, nil
+}
+
+// firstBlockAtOrAfter returns the first block up to maxBlock whose timestamp
+// is not before t, or maxBlock+1 when every block is older
+func (b *Backfill) firstBlockAtOrAfter(ctx context.Context, t time.Time, maxBlock uint64) (uint64, error) {
+	target := uint64(max(t.Unix(), 0))
+
+	lo, hi := uint64(0), maxBlock+1
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		timestamp, err := b.blockTimes.Lookup(ctx, mid)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get timestamp of block %d: %w", mid, err)
+		}
+		if timestamp >= target {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+
+	return lo, nil
+}
@@ -0,0 +1,131 @@
+package eth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/rs/zerolog"
+)
+
+// rpcRequest is a JSON-RPC request received by the fake RPC server
+type rpcRequest struct {
+	ID     json.RawMessage   `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+// newFakeRPC starts a JSON-RPC server answering single and batch requests
+// with handle and returns a client connected to it
+func newFakeRPC(t *testing.T, handle func(req rpcRequest) (interface{}, error)) *ethclient.Client {
+	t.Helper()
+
+	respond := func(req rpcRequest) map[string]interface{} {
+		result, err := handle(req)
+		if err != nil {
+			return map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "error": map[string]interface{}{"code": -32000, "message": err.Error()}}
+		}
+		return map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": result}
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+			return
+		}
+
+		if bytes.HasPrefix(bytes.TrimSpace(body), []byte("[")) {
+			var requests []rpcRequest
+			json.Unmarshal(body, &requests)
+			responses := make([]map[string]interface{}, len(requests))
+			for i, req := range requests {
+				responses[i] = respond(req)
+			}
+			json.NewEncoder(w).Encode(responses)
+			return
+		}
+
+		var req rpcRequest
+		json.Unmarshal(body, &req)
+		json.NewEncoder(w).Encode(respond(req))
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := ethclient.Dial(server.URL)
+	if err != nil {
+		t.Fatalf("failed to dial fake RPC: %v", err)
+	}
+	t.Cleanup(client.Close)
+	return client
+}
+
+// fakeChain answers eth_blockNumber and eth_getBlockByNumber for a chain
+// whose block n has timestamp genesis + n*blockTime
+func fakeChain(head, genesis, blockTime uint64) func(req rpcRequest) (interface{}, error) {
+	return func(req rpcRequest) (interface{}, error) {
+		switch req.Method {
+		case "eth_blockNumber":
+			return hexutil.EncodeUint64(head), nil
+		case "eth_getBlockByNumber":
+			var number string
+			json.Unmarshal(req.Params[0], &number)
+			n, err := strconv.ParseUint(strings.TrimPrefix(number, "0x"), 16, 64)
+			if err != nil || n > head {
+				return nil, nil
+			}
+			return map[string]interface{}{
+				"hash":      fmt.Sprintf("0x%064x", n),
+				"timestamp": hexutil.EncodeUint64(genesis + n*blockTime),
+			}, nil
+		}
+		return nil, fmt.Errorf("unexpected method %s", req.Method)
+	}
+}
+
+func TestBackfillResolveRange(t *testing.T) {
+	const (
+		head      = 100000
+		genesis   = 1700000000
+		blockTime = 2
+	)
+	rpcClient := newFakeRPC(t, fakeChain(head, genesis, blockTime))
+	blockAt := func(n uint64) time.Time { return time.Unix(int64(genesis+n*blockTime), 0) }
+
+	tests := []struct {
+		name     string
+		depth    uint64
+		opts     []RangeOption
+		wantFrom uint64
+		wantTo   uint64
+	}{
+		{"block range", 12, []RangeOption{WithBlockRange(10, 20)}, 10, 20},
+		{"block range capped at depth", 12, []RangeOption{WithBlockRange(10, 0)}, 10, head - 12},
+		{"time range", 12, []RangeOption{WithTimeRange(blockAt(500), blockAt(600))}, 500, 600},
+		{"time between blocks", 12, []RangeOption{WithTimeRange(blockAt(500).Add(time.Second), blockAt(600).Add(time.Second))}, 501, 600},
+		{"open-ended time range", 64, []RangeOption{WithTimeRange(blockAt(99000), time.Time{})}, 99000, head - 64},
+		{"start before genesis", 12, []RangeOption{WithTimeRange(time.Unix(0, 0), blockAt(3))}, 0, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backfill := NewBackfill(rpcClient, nil, zerolog.Nop(), nil, time.Hour, 100, WithConfirmationDepth(tt.depth))
+			from, to, err := backfill.resolveRange(context.Background(), tt.opts...)
+			if err != nil {
+				t.Fatalf("resolveRange returned error: %v", err)
+			}
+			if from != tt.wantFrom || to != tt.wantTo {
+				t.Errorf("range = [%d, %d], want [%d, %d]", from, to, tt.wantFrom, tt.wantTo)
+			}
+		})
+	}
+}
@@ -0,0 +1,488 @@
+package eth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dawitel/alchemy-webhook/cache"
+	"github.com/dawitel/alchemy-webhook/checkpoint"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/rs/zerolog"
+)
+
+// transferTo returns an external transfer of 1 ETH to addr as returned by
+// alchemy_getAssetTransfers
+func transferTo(addr string, n int) map[string]interface{} {
+	hash := fmt.Sprintf("0x%064x", n)
+	return map[string]interface{}{
+		"blockNum": "0x10",
+		"uniqueId": hash + ":external",
+		"hash":     hash,
+		"from":     "0x" + testAddrA,
+		"to":       addr,
+		"value":    1,
+		"asset":    "ETH",
+		"category": "external",
+		"rawContract": map[string]interface{}{
+			"value":   "0xde0b6b3a7640000",
+			"decimal": "0x12",
+		},
+	}
+}
+
+func TestBackfillBatchesAddresses(t *testing.T) {
+	var mu sync.Mutex
+	var calls [][]string
+	chain := fakeChain(1000, 1700000000, 12)
+	rpcClient := newFakeRPC(t, func(req rpcRequest) (interface{}, error) {
+		if req.Method != "alchemy_getAssetTransfers" {
+			return chain(req)
+		}
+
+		var params struct {
+			ToAddress []string `json:"toAddress"`
+		}
+		json.Unmarshal(req.Params[0], &params)
+
+		mu.Lock()
+		calls = append(calls, params.ToAddress)
+		n := len(calls)
+		mu.Unlock()
+
+		transfers := make([]interface{}, len(params.ToAddress))
+		for i, addr := range params.ToAddress {
+			// Alchemy returns addresses in lowercase
+			transfers[i] = transferTo(strings.ToLower(addr), n*100+i)
+		}
+		return map[string]interface{}{"transfers": transfers}, nil
+	})
+
+	addresses := make([]string, 5)
+	for i := range addresses {
+		addresses[i] = fmt.Sprintf("0x%040x", i+1)
+	}
+
+	var delivered []string
+	processor := NewProcessor(zerolog.Nop(), nil, nil, func(ctx context.Context, event ProcessedActivity) error {
+		mu.Lock()
+		delivered = append(delivered, event.ToAddress)
+		mu.Unlock()
+		return nil
+	}, "eth-mainnet")
+
+	backfill := NewBackfill(rpcClient, processor, zerolog.Nop(), nil, time.Hour, 2,
+		WithWorkers(2),
+		WithRateLimit(0, 0),
+	)
+	if _, err := backfill.BackfillRange(context.Background(), addresses, WithBlockRange(1, 900)); err != nil {
+		t.Fatalf("BackfillRange returned error: %v", err)
+	}
+
+	if len(calls) != 3 {
+		t.Errorf("made %d alchemy_getAssetTransfers calls, want 3", len(calls))
+	}
+	for _, call := range calls {
+		if len(call) > 2 {
+			t.Errorf("call queried %d addresses, want at most 2", len(call))
+		}
+	}
+
+	if len(delivered) != len(addresses) {
+		t.Fatalf("delivered %d activities, want %d", len(delivered), len(addresses))
+	}
+	seen := make(map[string]bool)
+	for _, to := range delivered {
+		seen[to] = true
+	}
+	for _, addr := range addresses {
+		if !seen[addr] {
+			t.Errorf("no activity delivered to %s", addr)
+		}
+	}
+}
+
+func TestBackfillBothDirectionsMergesTransfers(t *testing.T) {
+	watchedA := "0x" + testAddrA
+	watchedB := "0x" + testAddrB
+	outsider := "0x" + testAddrC
+
+	transfer := func(from, to string, n int) map[string]interface{} {
+		tr := transferTo(to, n)
+		tr["from"] = from
+		return tr
+	}
+	incoming := transfer(outsider, watchedA, 1)
+	internal := transfer(watchedA, watchedB, 2)
+	outgoing := transfer(watchedB, outsider, 3)
+
+	chain := fakeChain(1000, 1700000000, 12)
+	rpcClient := newFakeRPC(t, func(req rpcRequest) (interface{}, error) {
+		if req.Method != "alchemy_getAssetTransfers" {
+			return chain(req)
+		}
+
+		var params struct {
+			ToAddress   []string `json:"toAddress"`
+			FromAddress []string `json:"fromAddress"`
+		}
+		json.Unmarshal(req.Params[0], &params)
+		switch {
+		case len(params.ToAddress) > 0 && len(params.FromAddress) == 0:
+			return map[string]interface{}{"transfers": []interface{}{incoming, internal}}, nil
+		case len(params.FromAddress) > 0 && len(params.ToAddress) == 0:
+			return map[string]interface{}{"transfers": []interface{}{internal, outgoing}}, nil
+		}
+		return nil, fmt.Errorf("unexpected params %s", req.Params[0])
+	})
+
+	tests := []struct {
+		direction BackfillDirection
+		want      []string
+	}{
+		{BackfillIncoming, []string{fmt.Sprintf("0x%064x", 1), fmt.Sprintf("0x%064x", 2)}},
+		{BackfillOutgoing, []string{fmt.Sprintf("0x%064x", 2), fmt.Sprintf("0x%064x", 3)}},
+		{BackfillBoth, []string{fmt.Sprintf("0x%064x", 1), fmt.Sprintf("0x%064x", 2), fmt.Sprintf("0x%064x", 3)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.direction), func(t *testing.T) {
+			var mu sync.Mutex
+			delivered := make(map[string]int)
+			processor := NewProcessor(zerolog.Nop(), nil, nil, func(ctx context.Context, event ProcessedActivity) error {
+				mu.Lock()
+				delivered[event.TxHash]++
+				mu.Unlock()
+				return nil
+			}, "eth-mainnet")
+
+			backfill := NewBackfill(rpcClient, processor, zerolog.Nop(), nil, time.Hour, 10,
+				WithDirection(tt.direction),
+				WithRateLimit(0, 0),
+			)
+			_, err := backfill.BackfillRange(context.Background(), []string{watchedA, watchedB}, WithBlockRange(1, 900))
+			if err != nil {
+				t.Fatalf("BackfillRange returned error: %v", err)
+			}
+
+			if len(delivered) != len(tt.want) {
+				t.Errorf("delivered %v, want %v", delivered, tt.want)
+			}
+			for _, hash := range tt.want {
+				if delivered[hash] != 1 {
+					t.Errorf("transaction %s delivered %d times, want once", hash, delivered[hash])
+				}
+			}
+		})
+	}
+}
+
+func TestBackfillStreamsPagesAndReportsTruncation(t *testing.T) {
+	watched := "0x" + testAddrB
+	nextKey := map[string]string{"": "p1", "p1": "p2", "p2": ""}
+	pageNumber := map[string]int{"": 1, "p1": 2, "p2": 3}
+	pageBlock := map[string]int{"": 100, "p1": 200, "p2": 300}
+
+	chain := fakeChain(1000, 1700000000, 12)
+	rpcClient := newFakeRPC(t, func(req rpcRequest) (interface{}, error) {
+		if req.Method != "alchemy_getAssetTransfers" {
+			return chain(req)
+		}
+
+		var params struct {
+			PageKey string `json:"pageKey"`
+		}
+		json.Unmarshal(req.Params[0], &params)
+		transfer := transferTo(watched, pageNumber[params.PageKey])
+		transfer["blockNum"] = fmt.Sprintf("0x%x", pageBlock[params.PageKey])
+		return map[string]interface{}{
+			"transfers": []interface{}{transfer},
+			"pageKey":   nextKey[params.PageKey],
+		}, nil
+	})
+
+	tests := []struct {
+		name          string
+		opts          []BackfillOption
+		wantProcessed int
+		wantFromBlock uint64 // zero when not truncated
+	}{
+		{"no limit", nil, 3, 0},
+		// Block 200 may continue on the page that was not read
+		{"page limit", []BackfillOption{WithPageLimit(2)}, 2, 200},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			processor := NewProcessor(zerolog.Nop(), nil, nil, func(ctx context.Context, event ProcessedActivity) error {
+				return nil
+			}, "eth-mainnet")
+
+			opts := append([]BackfillOption{WithRateLimit(0, 0)}, tt.opts...)
+			backfill := NewBackfill(rpcClient, processor, zerolog.Nop(), nil, time.Hour, 10, opts...)
+			result, err := backfill.BackfillRange(context.Background(), []string{watched}, WithBlockRange(1, 900))
+			if err != nil {
+				t.Fatalf("BackfillRange returned error: %v", err)
+			}
+
+			if result.Processed != tt.wantProcessed {
+				t.Errorf("processed %d transfers, want %d", result.Processed, tt.wantProcessed)
+			}
+			progress := backfill.Job().Progress()
+			if progress.Processed != tt.wantProcessed || progress.AddressesDone != 1 || progress.AddressesTotal != 1 {
+				t.Errorf("job progress = %+v, want %d processed and the address done", progress, tt.wantProcessed)
+			}
+			if tt.wantFromBlock == 0 {
+				if len(result.Truncated) != 0 {
+					t.Errorf("unexpected truncated queries %+v", result.Truncated)
+				}
+				return
+			}
+			if len(result.Truncated) != 1 || result.Truncated[0].FromBlock != tt.wantFromBlock {
+				t.Fatalf("truncated = %+v, want one query continuing from block %d", result.Truncated, tt.wantFromBlock)
+			}
+			if got := result.Truncated[0]; got.Direction != BackfillIncoming || len(got.Addresses) != 1 {
+				t.Errorf("truncated query = %+v, want the incoming query of one address", got)
+			}
+			if errs := backfill.Job().Errors(); len(errs) != 1 {
+				t.Errorf("address errors = %v, want the truncated address", errs)
+			}
+		})
+	}
+}
+
+func TestBackfillResumesFromCheckpoint(t *testing.T) {
+	watched := "0x" + testAddrB
+	pageBlock := map[string]uint64{"": 800, "p1": 900}
+	nextKey := map[string]string{"": "p1", "p1": ""}
+
+	var mu sync.Mutex
+	var fromBlocks []string
+	chain := fakeChain(1000, uint64(time.Now().Unix())-1000*12, 12)
+	rpcClient := newFakeRPC(t, func(req rpcRequest) (interface{}, error) {
+		if req.Method != "alchemy_getAssetTransfers" {
+			return chain(req)
+		}
+
+		var params struct {
+			FromBlock string `json:"fromBlock"`
+			PageKey   string `json:"pageKey"`
+		}
+		json.Unmarshal(req.Params[0], &params)
+		if params.PageKey == "" {
+			mu.Lock()
+			fromBlocks = append(fromBlocks, params.FromBlock)
+			mu.Unlock()
+		}
+
+		transfer := transferTo(watched, int(pageBlock[params.PageKey]))
+		transfer["blockNum"] = fmt.Sprintf("0x%x", pageBlock[params.PageKey])
+		return map[string]interface{}{
+			"transfers": []interface{}{transfer},
+			"pageKey":   nextKey[params.PageKey],
+		}, nil
+	})
+
+	processor := NewProcessor(zerolog.Nop(), nil, nil, func(ctx context.Context, event ProcessedActivity) error {
+		return nil
+	}, "eth-mainnet")
+	store := checkpoint.NewMemoryStore()
+	newBackfill := func(opts ...BackfillOption) *Backfill {
+		opts = append([]BackfillOption{WithRateLimit(0, 0), WithCheckpoints(store)}, opts...)
+		return NewBackfill(rpcClient, processor, zerolog.Nop(), nil, time.Hour, 10, opts...)
+	}
+
+	// The first run stops after the page ending in block 800
+	if _, err := newBackfill(WithPageLimit(1)).BackfillRange(context.Background(), []string{watched}); err != nil {
+		t.Fatalf("BackfillRange returned error: %v", err)
+	}
+	cp, ok, err := store.Get(context.Background(), "eth-mainnet:incoming:"+watched)
+	if err != nil || !ok || cp.Height != 799 {
+		t.Fatalf("checkpoint after truncated run = %+v, %v, %v, want height 799", cp, ok, err)
+	}
+
+	// The second run starts at block 800 and completes up to the safe head
+	result, err := newBackfill().BackfillRange(context.Background(), []string{watched})
+	if err != nil {
+		t.Fatalf("BackfillRange returned error: %v", err)
+	}
+	if len(fromBlocks) != 2 || fromBlocks[1] != "0x320" {
+		t.Fatalf("queries started at %v, want the second at 0x320", fromBlocks)
+	}
+	cp, _, _ = store.Get(context.Background(), "eth-mainnet:incoming:"+watched)
+	if cp.Height != result.ToBlock {
+		t.Fatalf("checkpoint after complete run = %d, want %d", cp.Height, result.ToBlock)
+	}
+
+	// A third run has nothing left to query
+	if _, err := newBackfill().BackfillRange(context.Background(), []string{watched}); err != nil {
+		t.Fatalf("BackfillRange returned error: %v", err)
+	}
+	if len(fromBlocks) != 2 {
+		t.Errorf("queries started at %v, want no query once up to date", fromBlocks)
+	}
+
+	// An explicit range neither reads nor moves checkpoints
+	if _, err := newBackfill().BackfillRange(context.Background(), []string{watched}, WithBlockRange(1, 900)); err != nil {
+		t.Fatalf("BackfillRange returned error: %v", err)
+	}
+	if len(fromBlocks) != 3 || fromBlocks[2] != "0x1" {
+		t.Errorf("queries started at %v, want the explicit range at 0x1", fromBlocks)
+	}
+	if cp2, _, _ := store.Get(context.Background(), "eth-mainnet:incoming:"+watched); cp2.Height != cp.Height {
+		t.Errorf("explicit range moved checkpoint to %d, want %d", cp2.Height, cp.Height)
+	}
+}
+
+func TestInternalTransfersDedupeAcrossWebhookAndBackfill(t *testing.T) {
+	hash := "0x" + testHash
+	payload := `[
+		{"blockNum":"0x10","hash":"` + hash + `","fromAddress":"0x` + testAddrA + `","toAddress":"0x` + testAddrB + `","value":1,
+			"category":"internal","typeTraceAddress":"call_0","rawContract":{"rawValue":"0xde0b6b3a7640000","decimals":18}},
+		{"blockNum":"0x10","hash":"` + hash + `","fromAddress":"0x` + testAddrA + `","toAddress":"0x` + testAddrB + `","value":1,
+			"category":"internal","typeTraceAddress":"call_1","rawContract":{"rawValue":"0xde0b6b3a7640000","decimals":18}},
+		{"blockNum":"0x10","hash":"` + hash + `","fromAddress":"0x` + testAddrA + `","toAddress":"0x` + testAddrB + `","value":2,
+			"category":"internal","typeTraceAddress":"call_2","rawContract":{"rawValue":"0x1bc16d674ec80000","decimals":18}}
+	]`
+	var activities []AlchemyActivity
+	if err := json.Unmarshal([]byte(payload), &activities); err != nil {
+		t.Fatalf("failed to parse activities: %v", err)
+	}
+
+	internal := func(value json.Number, rawValue string) AlchemyAssetTransfer {
+		transfer := AlchemyAssetTransfer{
+			BlockNum: "0x10",
+			UniqueID: hash + ":internal",
+			Hash:     hash,
+			From:     "0x" + testAddrA,
+			To:       "0x" + testAddrB,
+			Value:    &value,
+			Asset:    "ETH",
+			Category: "internal",
+		}
+		transfer.RawContract.Value = rawValue
+		transfer.RawContract.Decimal = "0x12"
+		return transfer
+	}
+	transfers := []AlchemyAssetTransfer{
+		internal("1", "0xde0b6b3a7640000"),
+		internal("1", "0xde0b6b3a7640000"),
+		internal("2", "0x1bc16d674ec80000"),
+	}
+
+	memoryCache := cache.NewMemoryCache(100, time.Minute, false)
+	defer memoryCache.Close()
+
+	eventIDs := make(map[string]struct{})
+	processor := NewProcessor(zerolog.Nop(), memoryCache, nil, func(ctx context.Context, event ProcessedActivity) error {
+		eventIDs[event.EventID] = struct{}{}
+		return nil
+	}, "eth-mainnet")
+
+	if err := processor.ProcessActivities(context.Background(), activities); err != nil {
+		t.Fatalf("ProcessActivities returned error: %v", err)
+	}
+	if len(eventIDs) != 3 {
+		t.Fatalf("webhook delivered %d distinct events, want 3: %v", len(eventIDs), eventIDs)
+	}
+
+	backfill := NewBackfill(nil, processor, zerolog.Nop(), memoryCache, time.Hour, 10)
+	watched := map[common.Address]struct{}{common.HexToAddress(testAddrB): {}}
+	processed, skipped, failed := backfill.processTransfers(context.Background(), transfers, watched)
+	if processed != 0 || skipped != 3 || failed != 0 {
+		t.Errorf("backfill processed %d, skipped %d, failed %d, want the 3 transfers skipped", processed, skipped, failed)
+	}
+	if len(eventIDs) != 3 {
+		t.Errorf("backfill delivered new events, got %d distinct events: %v", len(eventIDs), eventIDs)
+	}
+}
+
+func TestBackfillPageFailureIsNotTruncation(t *testing.T) {
+	watched := "0x" + testAddrB
+	chain := fakeChain(1000, 1700000000, 12)
+	rpcClient := newFakeRPC(t, func(req rpcRequest) (interface{}, error) {
+		if req.Method != "alchemy_getAssetTransfers" {
+			return chain(req)
+		}
+
+		var params struct {
+			PageKey string `json:"pageKey"`
+		}
+		json.Unmarshal(req.Params[0], &params)
+		if params.PageKey != "" {
+			return nil, fmt.Errorf("upstream unavailable")
+		}
+		return map[string]interface{}{
+			"transfers": []interface{}{transferTo(watched, 1)},
+			"pageKey":   "p1",
+		}, nil
+	})
+
+	processor := NewProcessor(zerolog.Nop(), nil, nil, func(ctx context.Context, event ProcessedActivity) error {
+		return nil
+	}, "eth-mainnet")
+	backfill := NewBackfill(rpcClient, processor, zerolog.Nop(), nil, time.Hour, 10, WithRateLimit(0, 0), WithPageLimit(5))
+	result, err := backfill.BackfillRange(context.Background(), []string{watched}, WithBlockRange(1, 900))
+	if err != nil {
+		t.Fatalf("BackfillRange returned error: %v", err)
+	}
+
+	if result.Processed != 1 {
+		t.Errorf("processed %d transfers, want the 1 of the first page", result.Processed)
+	}
+	if len(result.Truncated) != 0 {
+		t.Errorf("failed query reported as truncated: %+v", result.Truncated)
+	}
+	errs := backfill.Job().Errors()
+	if err := errs[common.HexToAddress(watched).Hex()]; len(errs) != 1 || err == nil || !strings.Contains(err.Error(), "upstream unavailable") {
+		t.Errorf("address errors = %v, want the RPC failure only", errs)
+	}
+}
+
+func TestBackfillSkipsProcessedTransfers(t *testing.T) {
+	watched := "0x" + testAddrB
+	chain := fakeChain(1000, 1700000000, 12)
+	rpcClient := newFakeRPC(t, func(req rpcRequest) (interface{}, error) {
+		if req.Method != "alchemy_getAssetTransfers" {
+			return chain(req)
+		}
+		var transfers []interface{}
+		for n := 1; n <= 4; n++ {
+			transfers = append(transfers, transferTo(watched, n))
+		}
+		return map[string]interface{}{"transfers": transfers}, nil
+	})
+
+	memoryCache := cache.NewMemoryCache(100, time.Minute, false)
+	defer memoryCache.Close()
+	for _, n := range []int{2, 4} {
+		memoryCache.MarkProcessed(context.Background(), fmt.Sprintf("0x%064x:external", n), time.Hour)
+	}
+
+	var mu sync.Mutex
+	var delivered []string
+	processor := NewProcessor(zerolog.Nop(), memoryCache, nil, func(ctx context.Context, event ProcessedActivity) error {
+		mu.Lock()
+		defer mu.Unlock()
+		delivered = append(delivered, event.TxHash)
+		return nil
+	}, "eth-mainnet")
+
+	backfill := NewBackfill(rpcClient, processor, zerolog.Nop(), memoryCache, time.Hour, 10, WithRateLimit(0, 0))
+	result, err := backfill.BackfillRange(context.Background(), []string{watched}, WithBlockRange(1, 900))
+	if err != nil {
+		t.Fatalf("BackfillRange returned error: %v", err)
+	}
+
+	if result.Processed != 2 || result.Skipped != 2 {
+		t.Errorf("processed %d and skipped %d transfers, want 2 and 2", result.Processed, result.Skipped)
+	}
+	want := []string{fmt.Sprintf("0x%064x", 1), fmt.Sprintf("0x%064x", 3)}
+	if strings.Join(delivered, ",") != strings.Join(want, ",") {
+		t.Errorf("delivered %v, want %v", delivered, want)
+	}
+}
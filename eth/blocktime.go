@@ -0,0 +1,163 @@
+package eth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dawitel/alchemy-webhook/internal/lru"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/rs/zerolog"
+)
+
+const defaultBlockTimeCacheSize = 10000
+
+// WithBlockTimes looks up the block timestamp of activities whose payload
+// does not carry one. A receipt enricher fills timestamps on its own.
+func WithBlockTimes(blockTimes *BlockTimes) ProcessorOption {
+	return func(p *Processor) {
+		p.blockTimes = blockTimes
+	}
+}
+
+// BlockTimes looks up block timestamps with batched eth_getBlockByNumber
+// calls and caches them by block number
+type BlockTimes struct {
+	rpcClient *ethclient.Client
+	logger    zerolog.Logger
+	cache     *lru.Cache[uint64, cachedTimestamp]
+}
+
+// cachedTimestamp is a block timestamp together with the block hash
+type cachedTimestamp struct {
+	timestamp uint64
+	blockHash string
+}
+
+// blockHeader holds the fields read from eth_getBlockByNumber
+type blockHeader struct {
+	Hash      common.Hash    `json:"hash"`
+	Timestamp hexutil.Uint64 `json:"timestamp"`
+}
+
+// NewBlockTimes creates a block time lookup caching up to cacheSize blocks.
+// A cacheSize <= 0 uses the default.
+func NewBlockTimes(rpcClient *ethclient.Client, cacheSize int, logger zerolog.Logger) *BlockTimes {
+	if cacheSize <= 0 {
+		cacheSize = defaultBlockTimeCacheSize
+	}
+
+	return &BlockTimes{
+		rpcClient: rpcClient,
+		logger:    logger,
+		cache:     lru.New[uint64, cachedTimestamp](cacheSize),
+	}
+}
+
+// Lookup returns the timestamp of a block in Unix seconds
+func (b *BlockTimes) Lookup(ctx context.Context, number uint64) (uint64, error) {
+	timestamps, err := b.lookup(ctx, map[uint64]string{number: ""})
+	if err != nil {
+		return 0, err
+	}
+	cached, ok := timestamps[number]
+	if !ok {
+		return 0, fmt.Errorf("block %d not found", number)
+	}
+	return cached.timestamp, nil
+}
+
+// Fill sets BlockTimestamp on events that have none. Failed lookups are
+// logged and leave the timestamp at zero.
+func (b *BlockTimes) Fill(ctx context.Context, events []ProcessedActivity) {
+	if b.rpcClient == nil {
+		return
+	}
+
+	wanted := make(map[uint64]string)
+	for _, event := range events {
+		if event.BlockTimestamp == 0 && event.BlockNumber > 0 {
+			wanted[event.BlockNumber] = event.BlockHash
+		}
+	}
+	if len(wanted) == 0 {
+		return
+	}
+
+	timestamps, err := b.lookup(ctx, wanted)
+	if err != nil {
+		b.logger.Warn().Err(err).Msg("Failed to fetch block timestamps")
+	}
+
+	for i := range events {
+		if cached, ok := timestamps[events[i].BlockNumber]; ok && events[i].BlockTimestamp == 0 {
+			events[i].BlockTimestamp = cached.timestamp
+		}
+	}
+}
+
+// lookup returns the timestamps of the wanted block numbers. A cached entry
+// is used unless the wanted block hash is known and differs.
+func (b *BlockTimes) lookup(ctx context.Context, wanted map[uint64]string) (map[uint64]cachedTimestamp, error) {
+	if b.rpcClient == nil {
+		return nil, fmt.Errorf("RPC client not available")
+	}
+
+	found := make(map[uint64]cachedTimestamp, len(wanted))
+	var missing []uint64
+	for number, blockHash := range wanted {
+		cached, ok := b.cache.Get(number)
+		if ok && (blockHash == "" || cached.blockHash == blockHash) {
+			found[number] = cached
+			continue
+		}
+		missing = append(missing, number)
+	}
+
+	for start := 0; start < len(missing); start += maxRPCBatchSize {
+		numbers := missing[start:min(start+maxRPCBatchSize, len(missing))]
+		headers := make([]*blockHeader, len(numbers))
+		batch := make([]rpc.BatchElem, len(numbers))
+		for i, number := range numbers {
+			batch[i] = rpc.BatchElem{
+				Method: "eth_getBlockByNumber",
+				Args:   []interface{}{hexutil.EncodeUint64(number), false},
+				Result: &headers[i],
+			}
+		}
+
+		if err := b.rpcClient.Client().BatchCallContext(ctx, batch); err != nil {
+			return found, fmt.Errorf("failed to batch block requests: %w", err)
+		}
+
+		for i, number := range numbers {
+			if batch[i].Error != nil || headers[i] == nil {
+				continue
+			}
+			cached := cachedTimestamp{
+				timestamp: uint64(headers[i].Timestamp),
+				blockHash: strings.ToLower(headers[i].Hash.Hex()),
+			}
+			b.cache.Put(number, cached)
+			found[number] = cached
+		}
+	}
+
+	return found, nil
+}
+
+// parseBlockTimestamp parses an ISO 8601 block timestamp from Alchemy metadata
+func parseBlockTimestamp(value string) (uint64, bool) {
+	if value == "" {
+		return 0, false
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil || t.Unix() <= 0 {
+		return 0, false
+	}
+	return uint64(t.Unix()), true
+}
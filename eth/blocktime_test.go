@@ -0,0 +1,53 @@
+package eth
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestProcessActivityUsesPayloadBlockTimestamp(t *testing.T) {
+	payload := `{"blockNum":"0x10","hash":"0x` + testHash + `","fromAddress":"0x` + testAddrA + `","toAddress":"0x` + testAddrB + `",
+		"value":1,"category":"external","rawContract":{"rawValue":"0xde0b6b3a7640000","decimals":18},
+		"metadata":{"blockTimestamp":"2023-11-14T22:13:20.000Z"}}`
+
+	var activity AlchemyActivity
+	if err := json.Unmarshal([]byte(payload), &activity); err != nil {
+		t.Fatalf("failed to parse activity: %v", err)
+	}
+
+	var got []ProcessedActivity
+	processor := NewProcessor(zerolog.Nop(), nil, nil, func(ctx context.Context, event ProcessedActivity) error {
+		got = append(got, event)
+		return nil
+	}, "eth-mainnet", WithBlockTimes(NewBlockTimes(nil, 0, zerolog.Nop())))
+
+	if err := processor.ProcessActivity(context.Background(), activity); err != nil {
+		t.Fatalf("ProcessActivity returned error: %v", err)
+	}
+	if len(got) != 1 || got[0].BlockTimestamp != 1700000000 {
+		t.Fatalf("got %+v, want one activity with block timestamp 1700000000", got)
+	}
+}
+
+func TestParseBlockTimestamp(t *testing.T) {
+	tests := []struct {
+		value string
+		want  uint64
+		ok    bool
+	}{
+		{"2023-11-14T22:13:20.000Z", 1700000000, true},
+		{"2023-11-14T22:13:20Z", 1700000000, true},
+		{"", 0, false},
+		{"not a time", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseBlockTimestamp(tt.value)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseBlockTimestamp(%q) = %d, %v; want %d, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package eth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dawitel/alchemy-webhook/confirmation"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/rs/zerolog"
+)
+
+const defaultConfirmationPollInterval = 12 * time.Second
+
+// ConfirmationEvent is emitted when a processed activity reaches a
+// confirmation threshold or vanishes from the chain
+type ConfirmationEvent struct {
+	Activity      ProcessedActivity
+	Confirmations uint64
+	Threshold     uint64 // Threshold that was reached, zero when Dropped
+	Dropped       bool   // Transaction receipt is no longer found
+}
+
+// ConfirmationHandler is a callback function for confirmation events
+type ConfirmationHandler func(ctx context.Context, event ConfirmationEvent) error
+
+// WithConfirmationTracker records every delivered activity in tracker
+func WithConfirmationTracker(tracker *ConfirmationTracker) ProcessorOption {
+	return func(p *Processor) {
+		p.confirmations = tracker
+	}
+}
+
+// ConfirmationTracker polls the chain head and reports processed activities
+// as they reach configured confirmation thresholds. Before a threshold is
+// reported the receipt is fetched again to catch transactions that vanished.
+type ConfirmationTracker struct {
+	rpcClient    *ethclient.Client
+	store        confirmation.Store
+	thresholds   []uint64
+	pollInterval time.Duration
+	handler      ConfirmationHandler
+	logger       zerolog.Logger
+	mu           sync.Mutex
+	cancel       context.CancelFunc
+	done         chan struct{}
+}
+
+// NewConfirmationTracker creates a new confirmation tracker.
+// A nil store defaults to an in-memory store.
+func NewConfirmationTracker(
+	rpcClient *ethclient.Client,
+	store confirmation.Store,
+	thresholds []uint64,
+	pollInterval time.Duration,
+	handler ConfirmationHandler,
+	logger zerolog.Logger,
+) *ConfirmationTracker {
+	if store == nil {
+		store = confirmation.NewMemoryStore()
+	}
+	if pollInterval <= 0 {
+		pollInterval = defaultConfirmationPollInterval
+	}
+
+	return &ConfirmationTracker{
+		rpcClient:    rpcClient,
+		store:        store,
+		thresholds:   confirmation.NormalizeThresholds(thresholds),
+		pollInterval: pollInterval,
+		handler:      handler,
+		logger:       logger,
+	}
+}
+
+// Track records a processed activity awaiting confirmations
+func (t *ConfirmationTracker) Track(ctx context.Context, activity ProcessedActivity) error {
+	if len(t.thresholds) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(activity)
+	if err != nil {
+		return fmt.Errorf("failed to encode activity: %w", err)
+	}
+
+	return t.store.Put(ctx, confirmation.Record{
+		ID:        activity.EventID,
+		TxHash:    activity.TxHash,
+		Height:    activity.BlockNumber,
+		BlockHash: activity.BlockHash,
+		Payload:   payload,
+		CreatedAt: time.Now(),
+	})
+}
+
+// Start begins polling in the background until Stop is called or ctx is done
+func (t *ConfirmationTracker) Start(ctx context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.rpcClient == nil {
+		return fmt.Errorf("RPC client not available")
+	}
+	if t.cancel != nil {
+		return fmt.Errorf("confirmation tracker already started")
+	}
+
+	ctx, t.cancel = context.WithCancel(ctx)
+	t.done = make(chan struct{})
+
+	go func() {
+		defer close(t.done)
+
+		ticker := time.NewTicker(t.pollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := t.Poll(ctx); err != nil && ctx.Err() == nil {
+					t.logger.Warn().Err(err).Msg("Confirmation poll failed")
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Stop stops background polling and waits for the current poll to finish
+func (t *ConfirmationTracker) Stop() {
+	t.mu.Lock()
+	cancel, done := t.cancel, t.done
+	t.cancel = nil
+	t.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+}
+
+// Poll checks all pending records against the current chain head once
+func (t *ConfirmationTracker) Poll(ctx context.Context) error {
+	head, err := t.rpcClient.BlockNumber(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get current block number: %w", err)
+	}
+
+	records, err := t.store.List(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list pending confirmations: %w", err)
+	}
+
+	for _, record := range records {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := t.check(ctx, head, record); err != nil {
+			t.logger.Warn().
+				Err(err).
+				Str("unique_id", record.ID).
+				Msg("Failed to check confirmations")
+		}
+	}
+
+	return nil
+}
+
+// check reports the thresholds a record has reached since the last poll. A
+// transaction is reported dropped once its receipt is missing on
+// confirmation.DropAfterMisses consecutive checks.
+func (t *ConfirmationTracker) check(ctx context.Context, head uint64, record confirmation.Record) error {
+	if record.Reached >= len(t.thresholds) {
+		return t.store.Delete(ctx, record.ID)
+	}
+	if head < record.Height || head-record.Height+1 < t.thresholds[record.Reached] {
+		return nil
+	}
+
+	var activity ProcessedActivity
+	if err := json.Unmarshal(record.Payload, &activity); err != nil {
+		return fmt.Errorf("failed to decode activity: %w", err)
+	}
+
+	receipt, err := t.rpcClient.TransactionReceipt(ctx, common.HexToHash(record.TxHash))
+	if errors.Is(err, ethereum.NotFound) {
+		record.Misses++
+		if record.Misses < confirmation.DropAfterMisses {
+			t.logger.Debug().
+				Str("unique_id", record.ID).
+				Int("misses", record.Misses).
+				Msg("Transaction receipt not found, checking again")
+			return t.store.Put(ctx, record)
+		}
+
+		t.logger.Warn().
+			Str("unique_id", record.ID).
+			Uint64("block_number", record.Height).
+			Msg("Transaction vanished before reaching confirmations")
+		if err := t.emit(ctx, ConfirmationEvent{Activity: activity, Dropped: true}); err != nil {
+			return err
+		}
+		return t.store.Delete(ctx, record.ID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get transaction receipt: %w", err)
+	}
+	record.Misses = 0
+
+	// The transaction was re-included in another block; count from there
+	if receipt.BlockNumber != nil && (receipt.BlockNumber.Uint64() != record.Height ||
+		record.BlockHash != "" && !strings.EqualFold(receipt.BlockHash.Hex(), record.BlockHash)) {
+		record.Height = receipt.BlockNumber.Uint64()
+		record.BlockHash = strings.ToLower(receipt.BlockHash.Hex())
+		activity.BlockNumber = record.Height
+		activity.BlockHash = record.BlockHash
+		if payload, err := json.Marshal(activity); err == nil {
+			record.Payload = payload
+		}
+		if head < record.Height {
+			return t.store.Put(ctx, record)
+		}
+	}
+
+	confirmations := head - record.Height + 1
+	for record.Reached < len(t.thresholds) && confirmations >= t.thresholds[record.Reached] {
+		event := ConfirmationEvent{
+			Activity:      activity,
+			Confirmations: confirmations,
+			Threshold:     t.thresholds[record.Reached],
+		}
+		if err := t.emit(ctx, event); err != nil {
+			// Keep the thresholds reached so far so only this one is retried
+			if putErr := t.store.Put(ctx, record); putErr != nil {
+				return errors.Join(err, fmt.Errorf("failed to save confirmation record: %w", putErr))
+			}
+			return err
+		}
+		record.Reached++
+	}
+
+	if record.Reached >= len(t.thresholds) {
+		return t.store.Delete(ctx, record.ID)
+	}
+	return t.store.Put(ctx, record)
+}
+
+// emit calls the confirmation handler
+func (t *ConfirmationTracker) emit(ctx context.Context, event ConfirmationEvent) error {
+	if t.handler == nil {
+		return nil
+	}
+	if err := t.handler(ctx, event); err != nil {
+		return fmt.Errorf("confirmation handler error: %w", err)
+	}
+	return nil
+}
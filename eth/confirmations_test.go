@@ -0,0 +1,142 @@
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/dawitel/alchemy-webhook/confirmation"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/rs/zerolog"
+)
+
+// fakeReceiptChain answers eth_blockNumber and eth_getTransactionReceipt from
+// a head and the block the transaction is included in, zero when missing
+type fakeReceiptChain struct {
+	mu    sync.Mutex
+	head  uint64
+	block uint64
+}
+
+func (c *fakeReceiptChain) set(head, block uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.head, c.block = head, block
+}
+
+func (c *fakeReceiptChain) handle(req rpcRequest) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch req.Method {
+	case "eth_blockNumber":
+		return hexutil.EncodeUint64(c.head), nil
+	case "eth_getTransactionReceipt":
+		if c.block == 0 {
+			return nil, nil
+		}
+		return map[string]interface{}{
+			"transactionHash":   "0x" + testHash,
+			"blockHash":         fmt.Sprintf("0x%064x", c.block),
+			"blockNumber":       hexutil.EncodeUint64(c.block),
+			"status":            "0x1",
+			"gasUsed":           "0x5208",
+			"cumulativeGasUsed": "0x5208",
+			"logsBloom":         "0x" + strings.Repeat("00", 256),
+			"logs":              []interface{}{},
+		}, nil
+	}
+	return nil, fmt.Errorf("unexpected method %s", req.Method)
+}
+
+func TestConfirmationTrackerPoll(t *testing.T) {
+	type step struct {
+		head, block uint64
+		want        string // Reported events, e.g. "1@16" for threshold 1 at block 16, or "dropped"
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{"thresholds", []step{{16, 16, "1@16"}, {17, 16, ""}, {18, 16, "3@16"}, {19, 16, ""}}},
+		{"re-included", []step{{16, 16, "1@16"}, {18, 17, ""}, {19, 17, "3@17"}}},
+		{"dropped", []step{{16, 0, ""}, {17, 0, ""}, {18, 0, "dropped"}, {19, 16, ""}}},
+		{"miss then found", []step{{16, 0, ""}, {17, 0, ""}, {18, 16, "1@16 3@16"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain := &fakeReceiptChain{}
+			rpcClient := newFakeRPC(t, chain.handle)
+
+			var got []string
+			tracker := NewConfirmationTracker(rpcClient, nil, []uint64{1, 3}, 0, func(ctx context.Context, event ConfirmationEvent) error {
+				if event.Dropped {
+					got = append(got, "dropped")
+				} else {
+					got = append(got, fmt.Sprintf("%d@%d", event.Threshold, event.Activity.BlockNumber))
+				}
+				return nil
+			}, zerolog.Nop())
+
+			activity := ProcessedActivity{EventID: "a", TxHash: "0x" + testHash, BlockNumber: 16, BlockHash: fmt.Sprintf("0x%064x", 16)}
+			if err := tracker.Track(context.Background(), activity); err != nil {
+				t.Fatalf("Track returned error: %v", err)
+			}
+
+			for i, step := range tt.steps {
+				chain.set(step.head, step.block)
+				got = nil
+				if err := tracker.Poll(context.Background()); err != nil {
+					t.Fatalf("poll %d returned error: %v", i, err)
+				}
+				if strings.Join(got, " ") != step.want {
+					t.Errorf("poll %d at head %d reported %q, want %q", i, step.head, strings.Join(got, " "), step.want)
+				}
+			}
+		})
+	}
+}
+
+// failingStore is a confirmation store whose Put fails once err is set
+type failingStore struct {
+	*confirmation.MemoryStore
+	err error
+}
+
+func (s *failingStore) Put(ctx context.Context, record confirmation.Record) error {
+	if s.err != nil {
+		return s.err
+	}
+	return s.MemoryStore.Put(ctx, record)
+}
+
+func TestConfirmationTrackerReportsStoreFailure(t *testing.T) {
+	chain := &fakeReceiptChain{}
+	chain.set(16, 16)
+	rpcClient := newFakeRPC(t, chain.handle)
+
+	handlerErr := errors.New("handler down")
+	store := &failingStore{MemoryStore: confirmation.NewMemoryStore()}
+	tracker := NewConfirmationTracker(rpcClient, store, []uint64{1}, 0, func(ctx context.Context, event ConfirmationEvent) error {
+		return handlerErr
+	}, zerolog.Nop())
+
+	activity := ProcessedActivity{EventID: "a", TxHash: "0x" + testHash, BlockNumber: 16, BlockHash: fmt.Sprintf("0x%064x", 16)}
+	if err := tracker.Track(context.Background(), activity); err != nil {
+		t.Fatalf("Track returned error: %v", err)
+	}
+	records, err := store.List(context.Background())
+	if err != nil || len(records) != 1 {
+		t.Fatalf("List = %v, %v, want one record", records, err)
+	}
+
+	store.err = errors.New("store down")
+	err = tracker.check(context.Background(), 16, records[0])
+	if !errors.Is(err, handlerErr) || !errors.Is(err, store.err) {
+		t.Errorf("check error = %v, want both the handler and the store error", err)
+	}
+}
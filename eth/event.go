@@ -0,0 +1,88 @@
+package eth
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// activityEventID builds a stable unique ID for an activity that webhooks
+// and backfill agree on. Log-based transfers use the log index, in the same
+// "hash:log:index" form Alchemy uses for asset transfer unique IDs, and
+// internal transfers their content (see internalEventID).
+func activityEventID(txHash, category string, activity AlchemyActivity) string {
+	if activity.Log != nil {
+		if logIndex, ok := parseLogIndex(activity.Log.LogIndex); ok {
+			return fmt.Sprintf("%s:log:%d", txHash, logIndex)
+		}
+	}
+	if category == "internal" {
+		return internalEventID(txHash, activity)
+	}
+	if category == "" {
+		return txHash
+	}
+	return txHash + ":" + category
+}
+
+// internalEventID identifies an internal transfer by its sender, recipient
+// and wei value. Webhooks carry the trace address of internal transfers but
+// alchemy_getAssetTransfers does not, so the trace position cannot be used
+// on both paths. Identical transfers of one transaction are told apart by
+// their occurrence, see numberInternalTransfers.
+func internalEventID(txHash string, activity AlchemyActivity) string {
+	value := ""
+	if amount, _, err := activityAmount(activity, ethDecimals); err == nil && amount != nil {
+		value = amount.String()
+	}
+
+	id := fmt.Sprintf("%s:internal:%s:%s:%s", txHash, strings.ToLower(activity.FromAddress), strings.ToLower(activity.ToAddress), value)
+	if activity.occurrence > 0 {
+		id += ":" + strconv.Itoa(activity.occurrence)
+	}
+	return id
+}
+
+// numberInternalTransfers numbers the internal transfers that repeat an
+// earlier one of the same transaction, sender, recipient and value, in the
+// order of a webhook payload or a backfill page, so each keeps its own event
+// ID
+func numberInternalTransfers(activities []AlchemyActivity) {
+	seen := make(map[string]int)
+	for i := range activities {
+		if activities[i].Log != nil || strings.ToLower(activities[i].Category) != "internal" {
+			continue
+		}
+		activities[i].occurrence = 0
+		key := internalEventID(normalizeTxHash(activities[i].Hash), activities[i])
+		activities[i].occurrence = seen[key]
+		seen[key]++
+	}
+}
+
+// parseLogIndex parses a hex or decimal log index
+func parseLogIndex(logIndex string) (uint64, bool) {
+	if logIndex == "" {
+		return 0, false
+	}
+	if strings.HasPrefix(logIndex, "0x") {
+		idx, err := strconv.ParseUint(strings.TrimPrefix(logIndex, "0x"), 16, 64)
+		return idx, err == nil
+	}
+	idx, err := strconv.ParseUint(logIndex, 10, 64)
+	return idx, err == nil
+}
+
+// erc1155Token is a single token ID and amount of an ERC-1155 transfer
+type erc1155Token struct {
+	TokenID string
+	Value   *big.Int
+}
+
+// erc1155EventID extends an activity event ID with the index and token ID of
+// an entry, so every entry of an ERC-1155 batch transfer is deduplicated on
+// its own, including repeated token IDs
+func erc1155EventID(activityID string, index int, tokenID string) string {
+	return fmt.Sprintf("%s:%d:%s", activityID, index, strings.ToLower(tokenID))
+}
@@ -0,0 +1,120 @@
+package eth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dawitel/alchemy-webhook/cache"
+	"github.com/rs/zerolog"
+)
+
+func TestProcessERC1155Batch(t *testing.T) {
+	payload := `{"blockNum":"0x10","hash":"0x` + testHash + `","fromAddress":"0x` + testAddrA + `","toAddress":"0x` + testAddrB + `",
+		"category":"erc1155","asset":"ITEM","rawContract":{"address":"0x` + testAddrC + `"},
+		"erc1155Metadata":[{"tokenId":"0x1","value":"0xa"},{"tokenId":"0x2","value":"0x3"},{"tokenId":"0x1","value":"0x5"}],
+		"log":{"logIndex":"0x4","blockHash":"0xabc"}}`
+	var activity AlchemyActivity
+	if err := json.Unmarshal([]byte(payload), &activity); err != nil {
+		t.Fatalf("failed to parse activity: %v", err)
+	}
+
+	memoryCache := cache.NewMemoryCache(100, time.Minute, false)
+	defer memoryCache.Close()
+
+	var got []ProcessedActivity
+	processor := NewProcessor(zerolog.Nop(), memoryCache, nil, func(ctx context.Context, event ProcessedActivity) error {
+		got = append(got, event)
+		return nil
+	}, "eth-mainnet")
+
+	if err := processor.ProcessActivity(context.Background(), activity); err != nil {
+		t.Fatalf("ProcessActivity returned error: %v", err)
+	}
+
+	logID := fmt.Sprintf("0x%s:log:4", testHash)
+	want := []struct{ eventID, tokenID, value string }{
+		{logID + ":0:0x1", "0x1", "10"},
+		{logID + ":1:0x2", "0x2", "3"},
+		{logID + ":2:0x1", "0x1", "5"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d events, want %d", len(got), len(want))
+	}
+	for i, w := range want {
+		if got[i].EventID != w.eventID || got[i].TokenID != w.tokenID || got[i].Value != w.value {
+			t.Errorf("event %d = %s token %s value %s, want %s token %s value %s",
+				i, got[i].EventID, got[i].TokenID, got[i].Value, w.eventID, w.tokenID, w.value)
+		}
+	}
+
+	// Backfill checks the same keys the processor marks
+	for i, eventID := range activityEventIDs(activity) {
+		if eventID != want[i].eventID {
+			t.Errorf("backfill key %d = %s, want %s", i, eventID, want[i].eventID)
+		}
+	}
+
+	got = nil
+	if err := processor.ProcessActivity(context.Background(), activity); err != nil {
+		t.Fatalf("ProcessActivity returned error on redelivery: %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("redelivered batch produced %d events, want 0", len(got))
+	}
+}
+
+func TestLegacyEventIDs(t *testing.T) {
+	external := `{"blockNum":"0x10","hash":"0x` + testHash + `","fromAddress":"0x` + testAddrA + `","toAddress":"0x` + testAddrB + `",
+		"value":1,"category":"external"}`
+	internal := `{"blockNum":"0x10","hash":"0x` + testHash + `","fromAddress":"0x` + testAddrA + `","toAddress":"0x` + testAddrB + `",
+		"value":1,"category":"internal","typeTraceAddress":"call_0_1"}`
+
+	tests := []struct {
+		name        string
+		activity    string
+		legacyKey   string
+		until       time.Time
+		wantSkipped bool
+	}{
+		{"external in window", external, "0x" + testHash, time.Now().Add(time.Hour), true},
+		{"internal in window", internal, "0x" + testHash + "_call_0_1", time.Now().Add(time.Hour), true},
+		{"internal other trace", internal, "0x" + testHash + "_call_0_2", time.Now().Add(time.Hour), false},
+		{"window closed", external, "0x" + testHash, time.Now().Add(-time.Hour), false},
+		{"not configured", external, "0x" + testHash, time.Time{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var activity AlchemyActivity
+			if err := json.Unmarshal([]byte(tt.activity), &activity); err != nil {
+				t.Fatalf("failed to parse activity: %v", err)
+			}
+
+			memoryCache := cache.NewMemoryCache(100, time.Minute, false)
+			defer memoryCache.Close()
+			memoryCache.MarkProcessed(context.Background(), tt.legacyKey, time.Hour)
+
+			var got []ProcessedActivity
+			processor := NewProcessor(zerolog.Nop(), memoryCache, nil, func(ctx context.Context, event ProcessedActivity) error {
+				got = append(got, event)
+				return nil
+			}, "eth-mainnet", WithLegacyEventIDs(tt.until))
+
+			if err := processor.ProcessActivities(context.Background(), []AlchemyActivity{activity}); err != nil {
+				t.Fatalf("ProcessActivities returned error: %v", err)
+			}
+			if skipped := len(got) == 0; skipped != tt.wantSkipped {
+				t.Fatalf("skipped = %v, want %v", skipped, tt.wantSkipped)
+			}
+
+			// Skipped events are marked under their event ID as well
+			eventID := activityEventIDs(activity)[0]
+			if processed, _ := memoryCache.IsProcessed(context.Background(), eventID); !processed {
+				t.Errorf("event ID %s not marked as processed", eventID)
+			}
+		})
+	}
+}
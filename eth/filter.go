@@ -0,0 +1,228 @@
+package eth
+
+import (
+	"fmt"
+	"math/big"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/dawitel/alchemy-webhook/watch"
+)
+
+// Drop reasons reported by Processor.DropCounts
+const (
+	DropReasonUnsupportedCategory = "unsupported_category"
+	DropReasonMissingData         = "missing_data"
+	DropReasonTokenPolicy         = "token_policy"
+	DropReasonCategory            = "category"
+	DropReasonZeroValue           = "zero_value"
+	DropReasonMinAmount           = "min_amount"
+	DropReasonContract            = "contract"
+	DropReasonFromAddress         = "from_address"
+	DropReasonToAddress           = "to_address"
+	DropReasonInternal            = "internal"
+	DropReasonExternal            = "external"
+	DropReasonDirection           = "direction"
+)
+
+// FilterConfig declares which activities reach the handler. Empty fields
+// impose no restriction, so the zero value only drops zero-value transfers.
+type FilterConfig struct {
+	Categories        []string          // Alchemy categories to process, e.g. "external", "erc20"
+	MinAmounts        map[string]string // Currency -> minimum decimal amount, e.g. "USDC": "10"
+	ContractAllowlist []string          // When non-empty, token events from other contracts are dropped
+	ContractDenylist  []string          // Token events from these contracts are dropped
+	FromPatterns      []string          // When non-empty, the sender must match one of these patterns
+	ToPatterns        []string          // When non-empty, the recipient must match one of these patterns
+	ExcludeInternal   bool              // Drop internal transfers
+	ExcludeExternal   bool              // Drop top-level transfers
+	Directions        []watch.Direction // When non-empty, only these directions are delivered
+	KeepZeroValue     bool              // Deliver transfers with a zero value
+}
+
+// ActivityFilter applies a FilterConfig to processed activities.
+// Address patterns use path.Match syntax against lowercase addresses,
+// e.g. "0xdead*".
+type ActivityFilter struct {
+	categories    map[string]struct{}
+	minAmounts    map[string]*big.Rat
+	allow         map[string]struct{}
+	deny          map[string]struct{}
+	fromPatterns  []string
+	toPatterns    []string
+	excludeInt    bool
+	excludeExt    bool
+	directions    watch.Filter
+	keepZeroValue bool
+}
+
+// NewActivityFilter validates config and creates a filter
+func NewActivityFilter(config FilterConfig) (*ActivityFilter, error) {
+	f := &ActivityFilter{
+		categories:    make(map[string]struct{}, len(config.Categories)),
+		minAmounts:    make(map[string]*big.Rat, len(config.MinAmounts)),
+		allow:         make(map[string]struct{}, len(config.ContractAllowlist)),
+		deny:          make(map[string]struct{}, len(config.ContractDenylist)),
+		excludeInt:    config.ExcludeInternal,
+		excludeExt:    config.ExcludeExternal,
+		directions:    watch.NewFilter(config.Directions...),
+		keepZeroValue: config.KeepZeroValue,
+	}
+
+	for _, category := range config.Categories {
+		f.categories[strings.ToLower(category)] = struct{}{}
+	}
+	for currency, min := range config.MinAmounts {
+		minAmount, ok := new(big.Rat).SetString(min)
+		if !ok || minAmount.Sign() < 0 {
+			return nil, fmt.Errorf("invalid minimum amount for %s: %q", currency, min)
+		}
+		f.minAmounts[strings.ToUpper(currency)] = minAmount
+	}
+	for _, addr := range config.ContractAllowlist {
+		f.allow[strings.ToLower(addr)] = struct{}{}
+	}
+	for _, addr := range config.ContractDenylist {
+		f.deny[strings.ToLower(addr)] = struct{}{}
+	}
+
+	var err error
+	if f.fromPatterns, err = compilePatterns(config.FromPatterns); err != nil {
+		return nil, fmt.Errorf("invalid from pattern: %w", err)
+	}
+	if f.toPatterns, err = compilePatterns(config.ToPatterns); err != nil {
+		return nil, fmt.Errorf("invalid to pattern: %w", err)
+	}
+
+	return f, nil
+}
+
+// WithFilter applies filter to every activity before the handler runs.
+// It replaces the default filter, which only drops zero-value transfers.
+func WithFilter(filter *ActivityFilter) ProcessorOption {
+	return func(p *Processor) {
+		if filter != nil {
+			p.filter = filter
+		}
+	}
+}
+
+// allowsCategory reports whether a category is processed at all. It is
+// checked before token metadata is resolved.
+func (f *ActivityFilter) allowsCategory(category string) bool {
+	if len(f.categories) == 0 {
+		return true
+	}
+	_, ok := f.categories[category]
+	return ok
+}
+
+// Match returns the reason an activity is dropped, or an empty string when it passes
+func (f *ActivityFilter) Match(event ProcessedActivity) string {
+	if !f.allowsCategory(event.Category) {
+		return DropReasonCategory
+	}
+	if event.IsInternal && f.excludeInt {
+		return DropReasonInternal
+	}
+	if !event.IsInternal && f.excludeExt {
+		return DropReasonExternal
+	}
+
+	if event.ContractAddress != "" {
+		contract := strings.ToLower(event.ContractAddress)
+		if _, denied := f.deny[contract]; denied {
+			return DropReasonContract
+		}
+		if len(f.allow) > 0 {
+			if _, allowed := f.allow[contract]; !allowed {
+				return DropReasonContract
+			}
+		}
+	}
+
+	if !matchPatterns(f.fromPatterns, event.FromAddress) {
+		return DropReasonFromAddress
+	}
+	if !matchPatterns(f.toPatterns, event.ToAddress) {
+		return DropReasonToAddress
+	}
+	if !f.directions.Allows(event.Direction) {
+		return DropReasonDirection
+	}
+
+	value, ok := new(big.Int).SetString(event.Value, 10)
+	if !ok {
+		return DropReasonMissingData
+	}
+	if value.Sign() == 0 && !f.keepZeroValue {
+		return DropReasonZeroValue
+	}
+	if minAmount, ok := f.minAmounts[strings.ToUpper(event.Currency)]; ok {
+		scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(event.Decimals)), nil)
+		if new(big.Rat).SetFrac(value, scale).Cmp(minAmount) < 0 {
+			return DropReasonMinAmount
+		}
+	}
+
+	return ""
+}
+
+// compilePatterns lowercases address patterns and checks their syntax
+func compilePatterns(patterns []string) ([]string, error) {
+	compiled := make([]string, 0, len(patterns))
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("%q: %w", pattern, err)
+		}
+		compiled = append(compiled, pattern)
+	}
+	return compiled, nil
+}
+
+// matchPatterns reports whether addr matches any pattern; no patterns match everything
+func matchPatterns(patterns []string, addr string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	addr = strings.ToLower(addr)
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, addr); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// dropCounter counts dropped activities by reason
+type dropCounter struct {
+	mu     sync.Mutex
+	counts map[string]uint64
+}
+
+// newDropCounter creates an empty drop counter
+func newDropCounter() *dropCounter {
+	return &dropCounter{counts: make(map[string]uint64)}
+}
+
+// add counts n drops for reason
+func (c *dropCounter) add(reason string, n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.counts[reason] += uint64(n)
+}
+
+// snapshot returns a copy of the counts
+func (c *dropCounter) snapshot() map[string]uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	counts := make(map[string]uint64, len(c.counts))
+	for reason, n := range c.counts {
+		counts[reason] = n
+	}
+	return counts
+}
@@ -0,0 +1,110 @@
+package eth
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/dawitel/alchemy-webhook/watch"
+	"github.com/rs/zerolog"
+)
+
+func TestActivityFilterMatch(t *testing.T) {
+	usdc := ProcessedActivity{
+		Category:        "erc20",
+		FromAddress:     "0x" + testAddrA,
+		ToAddress:       "0x" + testAddrB,
+		ContractAddress: "0x" + testAddrC,
+		Currency:        "USDC",
+		Decimals:        6,
+		Value:           "9990000",
+		Direction:       watch.DirectionIncoming,
+	}
+	zero := usdc
+	zero.Value = "0"
+	internal := usdc
+	internal.IsInternal = true
+
+	tests := []struct {
+		name   string
+		config FilterConfig
+		event  ProcessedActivity
+		want   string
+	}{
+		{name: "default passes", event: usdc},
+		{name: "default drops zero value", event: zero, want: DropReasonZeroValue},
+		{name: "keep zero value", config: FilterConfig{KeepZeroValue: true}, event: zero},
+		{name: "category", config: FilterConfig{Categories: []string{"external"}}, event: usdc, want: DropReasonCategory},
+		{name: "below minimum", config: FilterConfig{MinAmounts: map[string]string{"usdc": "10"}}, event: usdc, want: DropReasonMinAmount},
+		{name: "above minimum", config: FilterConfig{MinAmounts: map[string]string{"USDC": "9.99"}}, event: usdc},
+		{name: "denied contract", config: FilterConfig{ContractDenylist: []string{"0x" + testAddrC}}, event: usdc, want: DropReasonContract},
+		{name: "not allowed contract", config: FilterConfig{ContractAllowlist: []string{"0x" + testAddrA}}, event: usdc, want: DropReasonContract},
+		{name: "from pattern", config: FilterConfig{FromPatterns: []string{"0x7166*"}}, event: usdc},
+		{name: "to pattern", config: FilterConfig{ToPatterns: []string{"0x7166*"}}, event: usdc, want: DropReasonToAddress},
+		{name: "exclude internal", config: FilterConfig{ExcludeInternal: true}, event: internal, want: DropReasonInternal},
+		{name: "exclude external", config: FilterConfig{ExcludeExternal: true}, event: usdc, want: DropReasonExternal},
+		{name: "direction", config: FilterConfig{Directions: []watch.Direction{watch.DirectionOutgoing}}, event: usdc, want: DropReasonDirection},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := NewActivityFilter(tt.config)
+			if err != nil {
+				t.Fatalf("NewActivityFilter returned error: %v", err)
+			}
+			if got := filter.Match(tt.event); got != tt.want {
+				t.Errorf("Match() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewActivityFilterRejectsInvalidConfig(t *testing.T) {
+	configs := []FilterConfig{
+		{MinAmounts: map[string]string{"ETH": "abc"}},
+		{MinAmounts: map[string]string{"ETH": "-1"}},
+		{FromPatterns: []string{"0x["}},
+	}
+
+	for _, config := range configs {
+		if _, err := NewActivityFilter(config); err == nil {
+			t.Errorf("NewActivityFilter(%+v) expected error", config)
+		}
+	}
+}
+
+func TestProcessorDirectionFilter(t *testing.T) {
+	transfer := func(from, to string) AlchemyActivity {
+		payload := `{"blockNum":"0x10","hash":"0x` + testHash + `","fromAddress":"0x` + from + `","toAddress":"0x` + to + `",
+			"value":1,"category":"external","rawContract":{"rawValue":"0xde0b6b3a7640000","decimals":18}}`
+		var activity AlchemyActivity
+		if err := json.Unmarshal([]byte(payload), &activity); err != nil {
+			t.Fatalf("failed to parse activity: %v", err)
+		}
+		return activity
+	}
+
+	var got []ProcessedActivity
+	watched := watch.NewSet(watch.LowerCase, "0x"+strings.ToUpper(testAddrB))
+	processor := NewProcessor(zerolog.Nop(), nil, nil, func(ctx context.Context, event ProcessedActivity) error {
+		got = append(got, event)
+		return nil
+	}, "eth-mainnet", WithAddressSet(watched), WithDirectionFilter(watch.DirectionIncoming))
+
+	for _, activity := range []AlchemyActivity{transfer(testAddrA, testAddrB), transfer(testAddrB, testAddrA), transfer(testAddrA, testAddrC)} {
+		if err := processor.ProcessActivity(context.Background(), activity); err != nil {
+			t.Fatalf("ProcessActivity returned error: %v", err)
+		}
+	}
+
+	if len(got) != 1 {
+		t.Fatalf("delivered %d activities, want the incoming one", len(got))
+	}
+	if got[0].Direction != watch.DirectionIncoming || got[0].WatchedAddress != "0x"+testAddrB {
+		t.Errorf("delivered %s to %s, want incoming to 0x%s", got[0].Direction, got[0].WatchedAddress, testAddrB)
+	}
+	if drops := processor.DropCounts()[DropReasonDirection]; drops != 2 {
+		t.Errorf("dropped %d activities by direction, want 2", drops)
+	}
+}
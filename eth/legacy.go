@@ -0,0 +1,52 @@
+package eth
+
+import (
+	"context"
+	"time"
+)
+
+// WithLegacyEventIDs also checks the dedupe keys of releases before event
+// IDs were introduced, the transaction hash or hash_traceAddress, until the
+// given time. Set it to the upgrade time plus the dedupe TTL so retries and
+// backfills of activities delivered before the upgrade are not delivered
+// again. An event matching a legacy key is skipped and marked under its
+// event ID.
+func WithLegacyEventIDs(until time.Time) ProcessorOption {
+	return func(p *Processor) {
+		p.legacyUntil = until
+	}
+}
+
+// legacyEventID returns the dedupe key an event had before event IDs
+func legacyEventID(event ProcessedActivity) string {
+	if event.TraceAddress != "" {
+		return event.TxHash + "_" + event.TraceAddress
+	}
+	return event.TxHash
+}
+
+// legacyProcessed reports whether an event was processed under its legacy
+// dedupe key while the transition window is open. A match is marked under
+// the event ID so it stays deduplicated after the window closes.
+func (p *Processor) legacyProcessed(ctx context.Context, event ProcessedActivity) bool {
+	if p.cache == nil || p.legacyUntil.IsZero() || !time.Now().Before(p.legacyUntil) {
+		return false
+	}
+
+	processed, err := p.cache.IsProcessed(ctx, legacyEventID(event))
+	if err != nil {
+		p.logger.Warn().
+			Err(err).
+			Str("unique_id", event.EventID).
+			Msg("Failed to check legacy dedupe key, continuing")
+		return false
+	}
+	if !processed {
+		return false
+	}
+
+	if err := p.cache.MarkProcessed(ctx, event.EventID, processedTTL); err != nil {
+		p.logger.Warn().Err(err).Str("unique_id", event.EventID).Msg("Failed to mark transaction as processed")
+	}
+	return true
+}
@@ -9,20 +9,65 @@ import (
 	"time"
 
 	"github.com/dawitel/alchemy-webhook/cache"
+	"github.com/dawitel/alchemy-webhook/dispatch"
+	"github.com/dawitel/alchemy-webhook/watch"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/rs/zerolog"
 )
 
+// processedTTL is how long delivered event IDs are remembered for deduplication
+const processedTTL = 24 * time.Hour
+
 // ActivityHandler is a callback function for processed activities
 type ActivityHandler func(ctx context.Context, activity ProcessedActivity) error
 
 // Processor processes Ethereum webhook activities
 type Processor struct {
-	logger         zerolog.Logger
-	cache          cache.Cache
-	tokenAddresses map[string]common.Address // symbol -> address mapping
-	handler        ActivityHandler
-	chainID        string
+	logger            zerolog.Logger
+	cache             cache.Cache
+	tokens            *TokenRegistry
+	subscribers       *dispatch.Dispatcher[ProcessedActivity]
+	txHandler         TransactionHandler
+	enricher          *ReceiptEnricher
+	blockTimes        *BlockTimes
+	retractionHandler RetractionHandler
+	blocks            *blockTracker
+	confirmations     *ConfirmationTracker
+	watched           watch.AddressSet
+	directions        watch.Filter
+	filter            *ActivityFilter
+	drops             *dropCounter
+	addressFormat     AddressFormat
+	legacyUntil       time.Time
+	chainID           string
+}
+
+// ProcessorOption configures optional Processor behaviour
+type ProcessorOption func(*Processor)
+
+// WithTokenRegistry sets the token registry used to resolve token metadata
+// and apply token policies. It replaces the registry built from tokenAddresses.
+func WithTokenRegistry(registry *TokenRegistry) ProcessorOption {
+	return func(p *Processor) {
+		p.tokens = registry
+	}
+}
+
+// WithAddressSet tags each activity with its direction relative to the
+// watched addresses, typically the addresses of the webhook
+func WithAddressSet(set watch.AddressSet) ProcessorOption {
+	return func(p *Processor) {
+		p.watched = set
+	}
+}
+
+// WithDirectionFilter delivers only activities with one of the given
+// directions. Without an address set no activity has a direction, so all are
+// dropped. FilterConfig.Directions is equivalent.
+func WithDirectionFilter(directions ...watch.Direction) ProcessorOption {
+	return func(p *Processor) {
+		p.directions = watch.NewFilter(directions...)
+	}
 }
 
 // NewProcessor creates a new Ethereum processor
@@ -32,28 +77,280 @@ func NewProcessor(
 	tokenAddresses map[string]string, // symbol -> address string
 	handler ActivityHandler,
 	chainID string,
+	opts ...ProcessorOption,
 ) *Processor {
-	tokenAddrs := make(map[string]common.Address)
-	for symbol, addr := range tokenAddresses {
-		tokenAddrs[symbol] = common.HexToAddress(addr)
+	p := &Processor{
+		logger:        logger,
+		cache:         cache,
+		tokens:        newTokenRegistryFromSymbols(tokenAddresses),
+		subscribers:   dispatch.New[ProcessedActivity](logger),
+		filter:        &ActivityFilter{}, // drops zero-value transfers only
+		drops:         newDropCounter(),
+		addressFormat: AddressFormatLowercase,
+		chainID:       chainID,
+	}
+
+	if handler != nil {
+		p.subscribers.Subscribe(Subscriber{Name: dispatch.DefaultSubscriberName, Handle: handler})
 	}
 
-	return &Processor{
-		logger:         logger,
-		cache:          cache,
-		tokenAddresses: tokenAddrs,
-		handler:        handler,
-		chainID:        chainID,
+	for _, opt := range opts {
+		opt(p)
 	}
+
+	return p
 }
 
 // ProcessActivity processes a single activity
 func (p *Processor) ProcessActivity(ctx context.Context, activity AlchemyActivity) error {
+	events, err := p.prepare(ctx, activity)
+	if err != nil {
+		return err
+	}
+	events = p.unprocessed(ctx, events)
+	p.enrich(ctx, events)
+
+	for _, event := range events {
+		if err := p.deliver(ctx, event); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// prepare converts an activity into the events to deliver: it builds them,
+// retracts removed logs, checks for reorgs, then classifies and filters them.
+// Reorgs are checked before filtering so the block hashes of filtered
+// activities are observed too.
+func (p *Processor) prepare(ctx context.Context, activity AlchemyActivity) ([]ProcessedActivity, error) {
+	events, reason, err := p.buildActivities(ctx, activity)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		p.drop(activity.Hash, activity.Category, reason)
+		return nil, nil
+	}
+
+	if activity.Log != nil && activity.Log.Removed {
+		for _, event := range p.classify(events) {
+			if err := p.retract(ctx, event, RetractionReasonRemoved, ""); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	}
+
+	for _, event := range events {
+		if err := p.checkReorg(ctx, event); err != nil {
+			return nil, err
+		}
+	}
+
+	return p.applyFilter(p.classify(events)), nil
+}
+
+// unprocessed drops the events that were already processed, looked up in
+// one batch, so retried payloads cost no enrichment calls. When the lookup
+// fails every event is kept.
+func (p *Processor) unprocessed(ctx context.Context, events []ProcessedActivity) []ProcessedActivity {
+	if p.cache == nil || len(events) == 0 {
+		return events
+	}
+
+	keys := make([]string, len(events))
+	for i, event := range events {
+		keys[i] = event.EventID
+	}
+
+	processed, err := cache.AsBatch(p.cache).IsProcessedBatch(ctx, keys)
+	if err != nil {
+		p.logger.Warn().
+			Err(err).
+			Str("hash", events[0].TxHash).
+			Msg("Failed to check if transaction is processed, continuing")
+		return events
+	}
+
+	pending := events[:0]
+	for i, event := range events {
+		if processed[i] || p.legacyProcessed(ctx, event) {
+			p.logger.Debug().
+				Str("unique_id", event.EventID).
+				Msg("Transaction already processed, skipping")
+			continue
+		}
+		pending = append(pending, event)
+	}
+	return pending
+}
+
+// enrich attaches receipts and block timestamps when an enricher or block
+// time lookup is configured
+func (p *Processor) enrich(ctx context.Context, events []ProcessedActivity) {
+	if p.enricher != nil {
+		p.enricher.Enrich(ctx, events)
+		return
+	}
+	if p.blockTimes != nil {
+		p.blockTimes.Fill(ctx, events)
+	}
+}
+
+// classify sets the direction of each event relative to the watched addresses
+func (p *Processor) classify(events []ProcessedActivity) []ProcessedActivity {
+	if p.watched == nil {
+		return events
+	}
+
+	for i := range events {
+		events[i].Direction, events[i].WatchedAddress = watch.Classify(p.watched, events[i].FromAddress, events[i].ToAddress)
+	}
+	return events
+}
+
+// applyFilter drops the events rejected by the direction filter or the activity filter
+func (p *Processor) applyFilter(events []ProcessedActivity) []ProcessedActivity {
+	kept := events[:0]
+	for _, event := range events {
+		reason := p.filter.Match(event)
+		if reason == "" && !p.directions.Allows(event.Direction) {
+			reason = DropReasonDirection
+		}
+		if reason != "" {
+			p.drop(event.EventID, event.Category, reason)
+			continue
+		}
+		kept = append(kept, event)
+	}
+	return kept
+}
+
+// drop counts and logs an activity that is not delivered
+func (p *Processor) drop(id, category, reason string) {
+	if reason == "" {
+		return
+	}
+	p.drops.add(reason, 1)
+	p.logger.Debug().
+		Str("unique_id", id).
+		Str("category", category).
+		Str("reason", reason).
+		Msg("Activity dropped")
+}
+
+// DropCounts returns the number of dropped activities by reason
+func (p *Processor) DropCounts() map[string]uint64 {
+	return p.drops.snapshot()
+}
+
+// checkReorg records the block hash of an event and retracts the events
+// delivered from a different block previously seen at the same height
+func (p *Processor) checkReorg(ctx context.Context, event ProcessedActivity) error {
+	if p.retractionHandler == nil || p.blocks == nil || event.BlockHash == "" {
+		return nil
+	}
+
+	replaced, replacedHash := p.blocks.observe(event.BlockNumber, event.BlockHash)
+	if replacedHash == "" {
+		return nil
+	}
+
+	p.logger.Warn().
+		Uint64("block_number", event.BlockNumber).
+		Str("old_block_hash", replacedHash).
+		Str("new_block_hash", event.BlockHash).
+		Int("retracted_events", len(replaced)).
+		Msg("Chain reorganization detected")
+
+	for _, old := range replaced {
+		if err := p.retract(ctx, old, RetractionReasonReorg, event.BlockHash); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// retract drops the dedupe mark of an event, so it is processed again if it
+// is re-included, and reports it to the retraction handler
+func (p *Processor) retract(ctx context.Context, event ProcessedActivity, reason, newBlockHash string) error {
+	if p.blocks != nil {
+		p.blocks.forget(event)
+	}
+
+	if inspector, ok := p.cache.(cache.Inspector); ok {
+		if err := inspector.Delete(ctx, event.EventID); err != nil {
+			p.logger.Warn().Err(err).Str("unique_id", event.EventID).Msg("Failed to delete dedupe key of retracted event")
+		}
+	}
+
+	p.logger.Info().
+		Str("unique_id", event.EventID).
+		Str("reason", reason).
+		Uint64("block_number", event.BlockNumber).
+		Msg("Retracting activity")
+
+	if p.retractionHandler == nil {
+		return nil
+	}
+
+	retraction := Retraction{
+		EventID:      event.EventID,
+		TxHash:       event.TxHash,
+		BlockNumber:  event.BlockNumber,
+		BlockHash:    event.BlockHash,
+		NewBlockHash: newBlockHash,
+		Reason:       reason,
+		Activity:     event,
+	}
+	if err := p.retractionHandler(ctx, retraction); err != nil {
+		return fmt.Errorf("retraction handler error: %w", err)
+	}
+
+	return nil
+}
+
+// deliver runs the handler for an activity that unprocessed kept and marks
+// it as processed afterwards
+func (p *Processor) deliver(ctx context.Context, event ProcessedActivity) error {
+	if err := p.subscribers.Dispatch(ctx, event); err != nil {
+		return fmt.Errorf("handler error: %w", err)
+	}
+
+	if p.cache != nil {
+		if err := p.cache.MarkProcessed(ctx, event.EventID, processedTTL); err != nil {
+			p.logger.Warn().Err(err).Str("unique_id", event.EventID).Msg("Failed to mark transaction as processed")
+		}
+	}
+
+	p.track(ctx, event)
+	return nil
+}
+
+// track records a delivered event for reorg and confirmation tracking
+func (p *Processor) track(ctx context.Context, event ProcessedActivity) {
+	if p.retractionHandler != nil && p.blocks != nil && event.BlockNumber > 0 {
+		p.blocks.record(event)
+	}
+
+	if p.confirmations != nil && event.BlockNumber > 0 {
+		if err := p.confirmations.Track(ctx, event); err != nil {
+			p.logger.Warn().Err(err).Str("unique_id", event.EventID).Msg("Failed to track confirmations")
+		}
+	}
+}
+
+// buildActivities validates an activity and converts it into processed
+// activities. Most activities yield one event; ERC-1155 batch transfers
+// yield one event per token ID. Unsupported or empty activities yield none.
+func (p *Processor) buildActivities(ctx context.Context, activity AlchemyActivity) ([]ProcessedActivity, string, error) {
 	if err := validateEthereumAddress(activity.ToAddress); err != nil {
-		return fmt.Errorf("invalid to address: %w", err)
+		return nil, "", fmt.Errorf("invalid to address: %w", err)
 	}
 	if err := validateEthereumAddress(activity.FromAddress); err != nil {
-		return fmt.Errorf("invalid from address: %w", err)
+		return nil, "", fmt.Errorf("invalid from address: %w", err)
 	}
 
 	txHash := strings.ToLower(strings.TrimPrefix(activity.Hash, "0x"))
@@ -62,49 +359,38 @@ func (p *Processor) ProcessActivity(ctx context.Context, activity AlchemyActivit
 	}
 
 	if err := validateTransactionHash(txHash); err != nil {
-		return fmt.Errorf("invalid transaction hash: %w", err)
+		return nil, "", fmt.Errorf("invalid transaction hash: %w", err)
 	}
 
-	uniqueID := txHash
-	if activity.TypeTraceAddress != nil && *activity.TypeTraceAddress != "" {
-		uniqueID = txHash + "_" + *activity.TypeTraceAddress
-	}
+	category := strings.ToLower(activity.Category)
+	uniqueID := activityEventID(txHash, category, activity)
 
-	if p.cache != nil {
-		processed, err := p.cache.IsProcessed(ctx, uniqueID)
-		if err != nil {
-			p.logger.Warn().
-				Err(err).
-				Str("unique_id", uniqueID).
-				Msg("Failed to check if transaction is processed, continuing")
-		} else if processed {
-			p.logger.Debug().
-				Str("unique_id", uniqueID).
-				Msg("Transaction already processed, skipping")
-			return nil
-		}
+	if !p.filter.allowsCategory(category) {
+		return nil, DropReasonCategory, nil
 	}
 
 	if err := validateBlockNumber(activity.BlockNum); err != nil {
-		return fmt.Errorf("invalid block number: %w", err)
+		return nil, "", fmt.Errorf("invalid block number: %w", err)
 	}
 
 	blockNumStr := strings.TrimPrefix(activity.BlockNum, "0x")
 	blockNum, err := strconv.ParseUint(blockNumStr, 16, 64)
 	if err != nil {
-		return fmt.Errorf("failed to parse block number: %w", err)
+		return nil, "", fmt.Errorf("failed to parse block number: %w", err)
 	}
 
 	var amount *big.Int
 	var currency string
 	var network string
+	var decimals int
+	var tokenID string
+	var erc1155Tokens []erc1155Token
 
-	category := strings.ToLower(activity.Category)
 	isInternalTx := category == "internal" || (activity.TypeTraceAddress != nil && *activity.TypeTraceAddress != "")
 
-	getDecimals := func() int {
+	getDecimals := func(fallback int) int {
 		if activity.RawContract == nil || activity.RawContract.Decimals == nil {
-			return 18
+			return fallback
 		}
 
 		switch v := activity.RawContract.Decimals.(type) {
@@ -123,20 +409,19 @@ func (p *Processor) ProcessActivity(ctx context.Context, activity AlchemyActivit
 				return dec
 			}
 		}
-		return 18
+		return fallback
 	}
 
 	if category == "external" || category == "internal" {
-		if activity.Value == nil {
-			return nil
+		amount, err = p.activityAmount(activity, ethDecimals)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid amount: %w", err)
 		}
-		ethValue := *activity.Value
-		ethValueWei := new(big.Float).Mul(big.NewFloat(ethValue), big.NewFloat(1e18))
-		amount, _ = ethValueWei.Int(nil)
 		if amount == nil {
-			amount = big.NewInt(0)
+			return nil, DropReasonMissingData, nil
 		}
 		currency = "ETH"
+		decimals = ethDecimals
 		if category == "internal" {
 			network = "INTERNAL"
 		} else {
@@ -149,61 +434,17 @@ func (p *Processor) ProcessActivity(ctx context.Context, activity AlchemyActivit
 				network = "TESTNET"
 			}
 		}
-	} else if category == "token" || category == "erc20" || (activity.RawContract != nil && activity.RawContract.Address != "") {
-		if activity.RawContract == nil {
-			return nil
-		}
-		rawValueStr := activity.RawContract.RawValue
-		if rawValueStr == "" {
-			if activity.Value != nil {
-				decimals := getDecimals()
-				tokenValue := *activity.Value
-				tokenValueRaw := new(big.Float).Mul(big.NewFloat(tokenValue), new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)))
-				amount, _ = tokenValueRaw.Int(nil)
-			} else {
-				return nil
-			}
-		} else {
-			rawValueStr = strings.TrimPrefix(rawValueStr, "0x")
-			amount, _ = new(big.Int).SetString(rawValueStr, 16)
-		}
-		if amount == nil {
-			amount = big.NewInt(0)
-		}
-
-		tokenAddr := common.HexToAddress(activity.RawContract.Address)
-		currency = p.getTokenSymbol(tokenAddr)
-		if currency == "" {
-			currency = activity.Asset
-			if currency == "" {
-				currency = "UNKNOWN"
-			}
-		}
-
-		if isInternalTx {
-			network = "ERC-20-INTERNAL"
-			if p.chainID == "eth-testnet" {
-				network = "ERC-20-INTERNAL-TESTNET"
-			}
-		} else {
-			network = "ERC-20"
-			if p.chainID == "eth-testnet" {
-				network = "ERC-20-TESTNET"
-			}
-		}
 	} else if category == "erc721" {
 		if activity.ERC721TokenID == nil || activity.RawContract == nil {
-			return nil
+			return nil, DropReasonMissingData, nil
 		}
-		amount = big.NewInt(1)
-		tokenAddr := common.HexToAddress(activity.RawContract.Address)
-		currency = p.getTokenSymbol(tokenAddr)
-		if currency == "" {
-			currency = activity.Asset
-			if currency == "" {
-				currency = "UNKNOWN"
-			}
+		token, allowed := p.resolveToken(ctx, activity, TokenTypeERC721)
+		if !allowed {
+			return nil, DropReasonTokenPolicy, nil
 		}
+		amount = big.NewInt(1)
+		currency = token.Symbol
+		tokenID = *activity.ERC721TokenID
 		if isInternalTx {
 			network = "ERC-721-INTERNAL"
 			if p.chainID == "eth-testnet" {
@@ -216,18 +457,24 @@ func (p *Processor) ProcessActivity(ctx context.Context, activity AlchemyActivit
 			}
 		}
 	} else if category == "erc1155" {
-		if activity.RawContract == nil {
-			return nil
+		if activity.RawContract == nil || len(activity.ERC1155Metadata) == 0 {
+			return nil, DropReasonMissingData, nil
 		}
-		amount = big.NewInt(1)
-		tokenAddr := common.HexToAddress(activity.RawContract.Address)
-		currency = p.getTokenSymbol(tokenAddr)
-		if currency == "" {
-			currency = activity.Asset
-			if currency == "" {
-				currency = "UNKNOWN"
+		token, allowed := p.resolveToken(ctx, activity, TokenTypeERC1155)
+		if !allowed {
+			return nil, DropReasonTokenPolicy, nil
+		}
+		for _, metadata := range activity.ERC1155Metadata {
+			value, err := parseRawValue(metadata.Value)
+			if err != nil {
+				return nil, "", fmt.Errorf("invalid ERC-1155 value for token %s: %w", metadata.TokenID, err)
 			}
+			erc1155Tokens = append(erc1155Tokens, erc1155Token{
+				TokenID: metadata.TokenID,
+				Value:   value,
+			})
 		}
+		currency = token.Symbol
 		if isInternalTx {
 			network = "ERC-1155-INTERNAL"
 			if p.chainID == "eth-testnet" {
@@ -239,51 +486,125 @@ func (p *Processor) ProcessActivity(ctx context.Context, activity AlchemyActivit
 				network = "ERC-1155-TESTNET"
 			}
 		}
-	} else {
-		p.logger.Debug().Str("category", category).Msg("Unsupported transaction category")
-		return nil
-	}
+	} else if category == "token" || category == "erc20" || (activity.RawContract != nil && activity.RawContract.Address != "") {
+		if activity.RawContract == nil {
+			return nil, DropReasonMissingData, nil
+		}
+		token, allowed := p.resolveToken(ctx, activity, TokenTypeERC20)
+		if !allowed {
+			return nil, DropReasonTokenPolicy, nil
+		}
+		currency = token.Symbol
 
-	if amount.Cmp(big.NewInt(0)) == 0 {
-		return nil
+		decimals = getDecimals(token.decimals(ethDecimals))
+		amount, err = p.activityAmount(activity, decimals)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid amount: %w", err)
+		}
+		if amount == nil {
+			return nil, DropReasonMissingData, nil
+		}
+
+		if isInternalTx {
+			network = "ERC-20-INTERNAL"
+			if p.chainID == "eth-testnet" {
+				network = "ERC-20-INTERNAL-TESTNET"
+			}
+		} else {
+			network = "ERC-20"
+			if p.chainID == "eth-testnet" {
+				network = "ERC-20-TESTNET"
+			}
+		}
+	} else {
+		return nil, DropReasonUnsupportedCategory, nil
 	}
 
-	processedActivity := ProcessedActivity{
+	base := ProcessedActivity{
+		EventID:     uniqueID,
 		TxHash:      txHash,
-		FromAddress: activity.FromAddress,
-		ToAddress:   activity.ToAddress,
-		Value:       amount.String(),
+		FromAddress: p.addressFormat.Normalize(activity.FromAddress),
+		ToAddress:   p.addressFormat.Normalize(activity.ToAddress),
 		Currency:    currency,
+		Decimals:    decimals,
 		Category:    category,
+		TokenID:     tokenID,
 		BlockNumber: blockNum,
 		Network:     network,
 		IsInternal:  isInternalTx,
 	}
 
-	if p.handler != nil {
-		if err := p.handler(ctx, processedActivity); err != nil {
-			return fmt.Errorf("handler error: %w", err)
+	if activity.RawContract != nil && activity.RawContract.Address != "" {
+		base.ContractAddress = p.addressFormat.Normalize(activity.RawContract.Address)
+	}
+	if activity.TypeTraceAddress != nil {
+		base.TraceAddress = *activity.TypeTraceAddress
+	}
+	if activity.Metadata != nil {
+		if timestamp, ok := parseBlockTimestamp(activity.Metadata.BlockTimestamp); ok {
+			base.BlockTimestamp = timestamp
+		}
+	}
+	if activity.Log != nil {
+		base.BlockHash = strings.ToLower(activity.Log.BlockHash)
+		if logIndex, ok := parseLogIndex(activity.Log.LogIndex); ok {
+			base.LogIndex = &logIndex
 		}
 	}
 
-	if p.cache != nil {
-		ttl := 24 * time.Hour
-		if err := p.cache.MarkProcessed(ctx, uniqueID, ttl); err != nil {
-			p.logger.Warn().Err(err).Str("unique_id", uniqueID).Msg("Failed to mark transaction as processed")
+	if erc1155Tokens != nil {
+		events := make([]ProcessedActivity, 0, len(erc1155Tokens))
+		for i, token := range erc1155Tokens {
+			event := base
+			event.EventID = erc1155EventID(uniqueID, i, token.TokenID)
+			event.TokenID = token.TokenID
+			event.Value = token.Value.String()
+			events = append(events, event)
 		}
+		return events, "", nil
 	}
 
-	return nil
+	base.Value = amount.String()
+	return []ProcessedActivity{base}, "", nil
 }
 
-// getTokenSymbol returns the token symbol for an address
-func (p *Processor) getTokenSymbol(tokenAddr common.Address) string {
-	for symbol, addr := range p.tokenAddresses {
-		if addr == tokenAddr {
-			return symbol
+// activityAmount returns the amount of an activity in base units, logging
+// a warning when the decimal value had to be truncated to decimals
+func (p *Processor) activityAmount(activity AlchemyActivity, decimals int) (*big.Int, error) {
+	amount, truncated, err := activityAmount(activity, decimals)
+	if truncated {
+		p.logger.Warn().
+			Str("hash", activity.Hash).
+			Str("value", activity.Value.String()).
+			Int("decimals", decimals).
+			Msg("Decimal value exceeds token decimals, truncating")
+	}
+	return amount, err
+}
+
+// resolveToken resolves the token of an activity through the registry and
+// applies the token policy. The symbol falls back to the activity asset.
+func (p *Processor) resolveToken(ctx context.Context, activity AlchemyActivity, tokenType string) (TokenInfo, bool) {
+	tokenAddr := common.HexToAddress(activity.RawContract.Address)
+
+	token, resolved := p.tokens.Resolve(ctx, tokenAddr, tokenType)
+	if !p.tokens.Allowed(tokenAddr, resolved) {
+		p.logger.Debug().
+			Str("contract", tokenAddr.Hex()).
+			Str("hash", activity.Hash).
+			Msg("Token dropped by token policy")
+		return token, false
+	}
+
+	token.Address = tokenAddr
+	if token.Symbol == "" {
+		token.Symbol = activity.Asset
+		if token.Symbol == "" {
+			token.Symbol = "UNKNOWN"
 		}
 	}
-	return ""
+
+	return token, true
 }
 
 // validateEthereumAddress validates an Ethereum address
@@ -0,0 +1,169 @@
+package eth
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/dawitel/alchemy-webhook/internal/lru"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/rs/zerolog"
+)
+
+const (
+	defaultReceiptCacheSize = 10000
+	// maxRPCBatchSize bounds the number of calls sent in one JSON-RPC batch
+	maxRPCBatchSize = 100
+)
+
+// TxReceipt holds the execution result of the transaction of an activity
+type TxReceipt struct {
+	Status            uint64 // 1 for success, 0 for failure
+	GasUsed           uint64
+	EffectiveGasPrice string // Wei, BigInt as string
+	Fee               string // Wei paid for execution and blob gas, BigInt as string
+}
+
+// Success reports whether the transaction succeeded
+func (r *TxReceipt) Success() bool {
+	return r.Status == types.ReceiptStatusSuccessful
+}
+
+// WithReceiptEnricher attaches receipts and block timestamps to activities
+// before they are delivered
+func WithReceiptEnricher(enricher *ReceiptEnricher) ProcessorOption {
+	return func(p *Processor) {
+		p.enricher = enricher
+	}
+}
+
+// ReceiptEnricher fetches transaction receipts and block timestamps in
+// JSON-RPC batches and caches them per transaction hash and block number
+type ReceiptEnricher struct {
+	rpcClient  *ethclient.Client
+	logger     zerolog.Logger
+	receipts   *lru.Cache[string, cachedReceipt]
+	blockTimes *BlockTimes
+}
+
+// cachedReceipt is a receipt together with the block it was read from
+type cachedReceipt struct {
+	receipt   TxReceipt
+	blockHash string
+}
+
+// NewReceiptEnricher creates a new receipt enricher caching up to cacheSize
+// receipts and block timestamps. A cacheSize <= 0 uses the default.
+func NewReceiptEnricher(rpcClient *ethclient.Client, cacheSize int, logger zerolog.Logger) *ReceiptEnricher {
+	if cacheSize <= 0 {
+		cacheSize = defaultReceiptCacheSize
+	}
+
+	return &ReceiptEnricher{
+		rpcClient:  rpcClient,
+		logger:     logger,
+		receipts:   lru.New[string, cachedReceipt](cacheSize),
+		blockTimes: NewBlockTimes(rpcClient, cacheSize, logger),
+	}
+}
+
+// Enrich sets Receipt and BlockTimestamp on events. Lookups that fail are
+// logged and leave the fields unset, so delivery is never blocked.
+func (e *ReceiptEnricher) Enrich(ctx context.Context, events []ProcessedActivity) {
+	if e.rpcClient == nil || len(events) == 0 {
+		return
+	}
+
+	receipts, err := e.fetchReceipts(ctx, events)
+	if err != nil {
+		e.logger.Warn().Err(err).Msg("Failed to fetch transaction receipts")
+	}
+	for i := range events {
+		if cached, ok := receipts[events[i].TxHash]; ok {
+			receipt := cached.receipt
+			events[i].Receipt = &receipt
+		}
+	}
+
+	e.blockTimes.Fill(ctx, events)
+}
+
+// fetchReceipts returns the receipts of the transactions of events, served
+// from the cache unless the event comes from a different block
+func (e *ReceiptEnricher) fetchReceipts(ctx context.Context, events []ProcessedActivity) (map[string]cachedReceipt, error) {
+	found := make(map[string]cachedReceipt)
+	seen := make(map[string]struct{})
+	var missing []string
+	for _, event := range events {
+		if _, ok := seen[event.TxHash]; ok {
+			continue
+		}
+		seen[event.TxHash] = struct{}{}
+
+		cached, ok := e.receipts.Get(event.TxHash)
+		if ok && (event.BlockHash == "" || cached.blockHash == event.BlockHash) {
+			found[event.TxHash] = cached
+			continue
+		}
+		missing = append(missing, event.TxHash)
+	}
+
+	for start := 0; start < len(missing); start += maxRPCBatchSize {
+		hashes := missing[start:min(start+maxRPCBatchSize, len(missing))]
+		receipts := make([]*types.Receipt, len(hashes))
+		batch := make([]rpc.BatchElem, len(hashes))
+		for i, hash := range hashes {
+			batch[i] = rpc.BatchElem{
+				Method: "eth_getTransactionReceipt",
+				Args:   []interface{}{common.HexToHash(hash)},
+				Result: &receipts[i],
+			}
+		}
+
+		if err := e.rpcClient.Client().BatchCallContext(ctx, batch); err != nil {
+			return found, fmt.Errorf("failed to batch receipt requests: %w", err)
+		}
+
+		for i, hash := range hashes {
+			if batch[i].Error != nil {
+				e.logger.Debug().Err(batch[i].Error).Str("hash", hash).Msg("Failed to fetch transaction receipt")
+				continue
+			}
+			if receipts[i] == nil {
+				continue
+			}
+			cached := cachedReceipt{
+				receipt:   newTxReceipt(receipts[i]),
+				blockHash: strings.ToLower(receipts[i].BlockHash.Hex()),
+			}
+			e.receipts.Put(hash, cached)
+			found[hash] = cached
+		}
+	}
+
+	return found, nil
+}
+
+// newTxReceipt converts a go-ethereum receipt
+func newTxReceipt(r *types.Receipt) TxReceipt {
+	receipt := TxReceipt{
+		Status:  r.Status,
+		GasUsed: r.GasUsed,
+	}
+
+	fee := new(big.Int)
+	if r.EffectiveGasPrice != nil {
+		receipt.EffectiveGasPrice = r.EffectiveGasPrice.String()
+		fee.Mul(new(big.Int).SetUint64(r.GasUsed), r.EffectiveGasPrice)
+	}
+	if r.BlobGasPrice != nil && r.BlobGasUsed > 0 {
+		fee.Add(fee, new(big.Int).Mul(new(big.Int).SetUint64(r.BlobGasUsed), r.BlobGasPrice))
+	}
+	receipt.Fee = fee.String()
+
+	return receipt
+}
@@ -0,0 +1,97 @@
+package eth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/rs/zerolog"
+)
+
+func TestReceiptEnricherBatchesAndCaches(t *testing.T) {
+	var batches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var requests []struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&requests); err != nil {
+			t.Errorf("expected a batch request: %v", err)
+			return
+		}
+		batches.Add(1)
+
+		responses := make([]map[string]interface{}, len(requests))
+		for i, req := range requests {
+			var result interface{}
+			switch req.Method {
+			case "eth_getTransactionReceipt":
+				result = map[string]interface{}{
+					"transactionHash":   "0x" + testHash,
+					"blockHash":         "0x" + strings.Repeat("ab", 32),
+					"blockNumber":       "0x10",
+					"status":            "0x0",
+					"gasUsed":           "0x5208",
+					"cumulativeGasUsed": "0x5208",
+					"effectiveGasPrice": "0x3b9aca00",
+					"logsBloom":         "0x" + strings.Repeat("00", 256),
+					"logs":              []interface{}{},
+				}
+			case "eth_getBlockByNumber":
+				result = map[string]interface{}{
+					"hash":      "0x" + strings.Repeat("ab", 32),
+					"timestamp": "0x6553f100",
+				}
+			}
+			responses[i] = map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": result}
+		}
+		json.NewEncoder(w).Encode(responses)
+	}))
+	defer server.Close()
+
+	rpcClient, err := ethclient.Dial(server.URL)
+	if err != nil {
+		t.Fatalf("failed to dial fake RPC: %v", err)
+	}
+	defer rpcClient.Close()
+
+	enricher := NewReceiptEnricher(rpcClient, 10, zerolog.Nop())
+	events := []ProcessedActivity{
+		{EventID: "a", TxHash: "0x" + testHash, BlockNumber: 16},
+		{EventID: "b", TxHash: "0x" + testHash, BlockNumber: 16},
+	}
+
+	enricher.Enrich(context.Background(), events)
+
+	for _, event := range events {
+		if event.Receipt == nil {
+			t.Fatalf("event %s has no receipt", event.EventID)
+		}
+		if event.Receipt.Success() || event.Receipt.GasUsed != 21000 {
+			t.Errorf("receipt = %+v, want failed with 21000 gas", event.Receipt)
+		}
+		if event.Receipt.Fee != "21000000000000" {
+			t.Errorf("fee = %s, want 21000000000000", event.Receipt.Fee)
+		}
+		if event.BlockTimestamp != 1700000000 {
+			t.Errorf("block timestamp = %d, want 1700000000", event.BlockTimestamp)
+		}
+	}
+	if n := batches.Load(); n != 2 {
+		t.Errorf("sent %d batches, want 2", n)
+	}
+
+	again := []ProcessedActivity{{EventID: "c", TxHash: "0x" + testHash, BlockNumber: 16}}
+	enricher.Enrich(context.Background(), again)
+	if again[0].Receipt == nil || again[0].BlockTimestamp == 0 {
+		t.Errorf("cached enrichment missing: %+v", again[0])
+	}
+	if n := batches.Load(); n != 2 {
+		t.Errorf("sent %d batches after cache hit, want 2", n)
+	}
+}
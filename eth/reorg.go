@@ -0,0 +1,170 @@
+package eth
+
+import (
+	"context"
+	"sync"
+)
+
+// Retraction reasons
+const (
+	// RetractionReasonRemoved means Alchemy sent the log again with removed set
+	RetractionReasonRemoved = "removed"
+	// RetractionReasonReorg means a different block hash arrived at an already seen height
+	RetractionReasonReorg = "reorg"
+)
+
+// defaultReorgWindow is the number of recent block heights whose hashes are kept
+const defaultReorgWindow = 64
+
+// Retraction represents a previously delivered activity that is no longer
+// part of the canonical chain
+type Retraction struct {
+	EventID      string
+	TxHash       string
+	BlockNumber  uint64
+	BlockHash    string // Block the activity was processed from
+	NewBlockHash string // Block that replaced it, set for reorg retractions
+	Reason       string
+	Activity     ProcessedActivity
+}
+
+// RetractionHandler is a callback function for retracted activities
+type RetractionHandler func(ctx context.Context, retraction Retraction) error
+
+// WithRetractionHandler enables reorg handling. Removed logs and events from
+// blocks replaced at an already seen height are reported to handler.
+func WithRetractionHandler(handler RetractionHandler) ProcessorOption {
+	return func(p *Processor) {
+		p.retractionHandler = handler
+		if p.blocks == nil {
+			p.blocks = newBlockTracker(defaultReorgWindow)
+		}
+	}
+}
+
+// WithReorgWindow sets how many recent block heights are tracked for reorgs
+func WithReorgWindow(blocks int) ProcessorOption {
+	return func(p *Processor) {
+		if blocks > 0 {
+			p.blocks = newBlockTracker(uint64(blocks))
+		}
+	}
+}
+
+// trackedBlock is a block seen at a height and the events delivered from it.
+// The hash is empty until an activity with a log reveals it.
+type trackedBlock struct {
+	hash   string
+	events []ProcessedActivity
+}
+
+// blockTracker keeps a short window of block hashes seen per height.
+// External and internal transfers carry no block hash; they are recorded by
+// block number and retracted with the hash first seen at their height. A
+// reorg at a height where no log-based activity arrives is not detected;
+// confirmation tracking catches those.
+type blockTracker struct {
+	mu      sync.Mutex
+	window  uint64
+	blocks  map[uint64]*trackedBlock
+	highest uint64
+}
+
+// newBlockTracker creates a tracker covering window block heights
+func newBlockTracker(window uint64) *blockTracker {
+	return &blockTracker{
+		window: window,
+		blocks: make(map[uint64]*trackedBlock),
+	}
+}
+
+// observe records the hash seen at a height. When it differs from the hash
+// recorded earlier, the events delivered from the replaced block are
+// returned together with the replaced hash.
+func (t *blockTracker) observe(height uint64, hash string) ([]ProcessedActivity, string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.highest >= t.window && height < t.highest-t.window {
+		return nil, ""
+	}
+
+	block, seen := t.blocks[height]
+	if seen && block.hash == hash {
+		return nil, ""
+	}
+	if seen && block.hash == "" {
+		block.hash = hash
+		return nil, ""
+	}
+
+	t.blocks[height] = &trackedBlock{hash: hash}
+	if height > t.highest {
+		t.highest = height
+		t.prune()
+	}
+
+	if !seen {
+		return nil, ""
+	}
+	return block.events, block.hash
+}
+
+// record adds a delivered event to the block it was processed from. Events
+// without a block hash are added to the block at their height.
+func (t *blockTracker) record(event ProcessedActivity) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	height := event.BlockNumber
+	if t.highest >= t.window && height < t.highest-t.window {
+		return
+	}
+
+	block, ok := t.blocks[height]
+	if !ok {
+		if event.BlockHash != "" {
+			return
+		}
+		block = &trackedBlock{}
+		t.blocks[height] = block
+		if height > t.highest {
+			t.highest = height
+			t.prune()
+		}
+	}
+
+	if event.BlockHash == "" || block.hash == event.BlockHash {
+		block.events = append(block.events, event)
+	}
+}
+
+// forget removes an event from the block it was recorded under
+func (t *blockTracker) forget(event ProcessedActivity) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	block, ok := t.blocks[event.BlockNumber]
+	if !ok {
+		return
+	}
+	for i, e := range block.events {
+		if e.EventID == event.EventID {
+			block.events = append(block.events[:i], block.events[i+1:]...)
+			return
+		}
+	}
+}
+
+// prune drops heights that fell out of the window; the caller must hold the lock
+func (t *blockTracker) prune() {
+	if t.highest < t.window {
+		return
+	}
+	cutoff := t.highest - t.window
+	for height := range t.blocks {
+		if height < cutoff {
+			delete(t.blocks, height)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package eth
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/dawitel/alchemy-webhook/cache"
+	"github.com/rs/zerolog"
+)
+
+// usdcTransfer returns an ERC-20 transfer of amount (hex, 6 decimals) at log
+// logIndex of block 0x10 with the given block hash
+func usdcTransfer(t *testing.T, blockHash, logIndex, amount string, removed bool) AlchemyActivity {
+	t.Helper()
+	removedJSON := "false"
+	if removed {
+		removedJSON = "true"
+	}
+	payload := `{"blockNum":"0x10","hash":"0x` + testHash + `","fromAddress":"0x` + testAddrA + `","toAddress":"0x` + testAddrB + `",
+		"category":"erc20","asset":"USDC","rawContract":{"rawValue":"` + amount + `","address":"0x` + testAddrC + `","decimals":6},
+		"log":{"logIndex":"` + logIndex + `","blockHash":"` + blockHash + `","removed":` + removedJSON + `}}`
+	var activity AlchemyActivity
+	if err := json.Unmarshal([]byte(payload), &activity); err != nil {
+		t.Fatalf("failed to parse activity: %v", err)
+	}
+	return activity
+}
+
+func TestBlockTrackerObserveAndPrune(t *testing.T) {
+	tracker := newBlockTracker(4)
+	event := ProcessedActivity{EventID: "a", BlockNumber: 10, BlockHash: "0xa"}
+
+	if replaced, hash := tracker.observe(10, "0xa"); replaced != nil || hash != "" {
+		t.Fatalf("first observation replaced %v (%s)", replaced, hash)
+	}
+	tracker.record(event)
+	if replaced, hash := tracker.observe(10, "0xa"); replaced != nil || hash != "" {
+		t.Fatalf("same hash replaced %v (%s)", replaced, hash)
+	}
+	replaced, hash := tracker.observe(10, "0xb")
+	if hash != "0xa" || len(replaced) != 1 || replaced[0].EventID != "a" {
+		t.Fatalf("new hash replaced %v (%s), want event a of 0xa", replaced, hash)
+	}
+
+	// An event without a hash adopts the first hash seen at its height
+	tracker.record(ProcessedActivity{EventID: "external", BlockNumber: 12})
+	if replaced, hash := tracker.observe(12, "0xc"); replaced != nil || hash != "" {
+		t.Fatalf("first hash at a recorded height replaced %v (%s)", replaced, hash)
+	}
+	replaced, hash = tracker.observe(12, "0xd")
+	if hash != "0xc" || len(replaced) != 1 || replaced[0].EventID != "external" {
+		t.Fatalf("new hash replaced %v (%s), want the external event of 0xc", replaced, hash)
+	}
+
+	tracker.observe(20, "0xe")
+	if _, ok := tracker.blocks[10]; ok {
+		t.Error("height 10 was not pruned after the window moved to 20")
+	}
+	if replaced, hash := tracker.observe(10, "0xf"); replaced != nil || hash != "" {
+		t.Errorf("height outside the window replaced %v (%s)", replaced, hash)
+	}
+	tracker.record(ProcessedActivity{EventID: "old", BlockNumber: 11})
+	if _, ok := tracker.blocks[11]; ok {
+		t.Error("event outside the window was recorded")
+	}
+}
+
+func TestProcessorRetractions(t *testing.T) {
+	const oldHash, newHash = "0xaaa", "0xbbb"
+	external := `{"blockNum":"0x10","hash":"0x` + testHash + `","fromAddress":"0x` + testAddrA + `","toAddress":"0x` + testAddrB + `",
+		"value":1,"category":"external","rawContract":{"rawValue":"0xde0b6b3a7640000","decimals":18}}`
+
+	tests := []struct {
+		name        string
+		replacement AlchemyActivity
+		wantReason  string
+		wantEvents  int
+	}{
+		{"removed log", usdcTransfer(t, oldHash, "0x1", "0x4c4b40", true), RetractionReasonRemoved, 1},
+		// The replacement is a zero-value transfer, which the filter drops after the reorg check
+		{"replaced block", usdcTransfer(t, newHash, "0x2", "0x0", false), RetractionReasonReorg, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			memoryCache := cache.NewMemoryCache(100, time.Minute, false)
+			defer memoryCache.Close()
+
+			var delivered int
+			var retractions []Retraction
+			processor := NewProcessor(zerolog.Nop(), memoryCache, nil, func(ctx context.Context, event ProcessedActivity) error {
+				delivered++
+				return nil
+			}, "eth-mainnet", WithRetractionHandler(func(ctx context.Context, retraction Retraction) error {
+				retractions = append(retractions, retraction)
+				return nil
+			}))
+
+			var externalActivity AlchemyActivity
+			if err := json.Unmarshal([]byte(external), &externalActivity); err != nil {
+				t.Fatalf("failed to parse activity: %v", err)
+			}
+			original := usdcTransfer(t, oldHash, "0x1", "0x4c4b40", false)
+			for _, activity := range []AlchemyActivity{original, externalActivity, tt.replacement} {
+				if err := processor.ProcessActivity(context.Background(), activity); err != nil {
+					t.Fatalf("ProcessActivity returned error: %v", err)
+				}
+			}
+			if delivered != 2 {
+				t.Fatalf("delivered %d events, want 2", delivered)
+			}
+
+			if len(retractions) != tt.wantEvents {
+				t.Fatalf("got %d retractions, want %d: %+v", len(retractions), tt.wantEvents, retractions)
+			}
+			for _, retraction := range retractions {
+				if retraction.Reason != tt.wantReason {
+					t.Errorf("retraction %s reason = %s, want %s", retraction.EventID, retraction.Reason, tt.wantReason)
+				}
+				if tt.wantReason == RetractionReasonReorg && retraction.NewBlockHash != newHash {
+					t.Errorf("retraction %s new block hash = %s, want %s", retraction.EventID, retraction.NewBlockHash, newHash)
+				}
+			}
+
+			// Retracted events are delivered again when re-included
+			if err := processor.ProcessActivity(context.Background(), original); err != nil {
+				t.Fatalf("ProcessActivity returned error: %v", err)
+			}
+			if delivered != 3 {
+				t.Errorf("re-included event delivered %d times in total, want 3", delivered)
+			}
+		})
+	}
+}
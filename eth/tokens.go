@@ -0,0 +1,261 @@
+package eth
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dawitel/alchemy-webhook/internal/lru"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Token types
+const (
+	TokenTypeERC20   = "ERC20"
+	TokenTypeERC721  = "ERC721"
+	TokenTypeERC1155 = "ERC1155"
+)
+
+const (
+	// Lookups run on the webhook path, so all calls share one short timeout
+	defaultTokenLookupTimeout = 2 * time.Second
+	defaultTokenRetryInterval = 1 * time.Hour
+	// defaultTokenCacheSize bounds the resolved and failed lookups kept
+	defaultTokenCacheSize = 10000
+)
+
+// ERC-20 function selectors used for metadata lookups
+var (
+	selectorSymbol   = common.FromHex("0x95d89b41")
+	selectorName     = common.FromHex("0x06fdde03")
+	selectorDecimals = common.FromHex("0x313ce567")
+)
+
+// TokenInfo holds metadata for a token contract. Without HasDecimals, a
+// Decimals value of zero is treated as unknown when computing amounts.
+type TokenInfo struct {
+	Address     common.Address
+	Symbol      string
+	Name        string
+	Decimals    int
+	HasDecimals bool // Decimals is known, so zero means an indivisible token
+	Type        string
+}
+
+// decimals returns the decimals of the token, or fallback when unknown
+func (t TokenInfo) decimals(fallback int) int {
+	if t.HasDecimals || t.Decimals > 0 {
+		return t.Decimals
+	}
+	return fallback
+}
+
+// TokenPolicy controls which token contracts reach the activity handler
+type TokenPolicy struct {
+	Allowlist      []string // When non-empty, only these contracts are delivered
+	Denylist       []string // These contracts are always dropped (e.g. spam tokens)
+	DropUnresolved bool     // Drop tokens that are neither registered nor resolvable via RPC
+}
+
+// TokenRegistry holds token metadata by contract address. Unknown tokens
+// are looked up with ERC-20 symbol()/decimals()/name() calls when an RPC
+// client is configured, and the results are kept in a bounded cache.
+type TokenRegistry struct {
+	mu            sync.RWMutex
+	tokens        map[common.Address]TokenInfo // Registered tokens, never evicted
+	resolved      *lru.Cache[common.Address, TokenInfo]
+	failed        *lru.Cache[common.Address, time.Time]
+	rpcClient     *ethclient.Client
+	allow         map[common.Address]struct{}
+	deny          map[common.Address]struct{}
+	dropUnknown   bool
+	lookupTimeout time.Duration
+	retryInterval time.Duration
+}
+
+// NewTokenRegistry creates a new token registry.
+// rpcClient may be nil, in which case only registered tokens are known.
+func NewTokenRegistry(rpcClient *ethclient.Client, tokens []TokenInfo, policy TokenPolicy) *TokenRegistry {
+	r := &TokenRegistry{
+		tokens:        make(map[common.Address]TokenInfo),
+		resolved:      lru.New[common.Address, TokenInfo](defaultTokenCacheSize),
+		failed:        lru.New[common.Address, time.Time](defaultTokenCacheSize),
+		rpcClient:     rpcClient,
+		allow:         make(map[common.Address]struct{}),
+		deny:          make(map[common.Address]struct{}),
+		dropUnknown:   policy.DropUnresolved,
+		lookupTimeout: defaultTokenLookupTimeout,
+		retryInterval: defaultTokenRetryInterval,
+	}
+
+	for _, token := range tokens {
+		r.tokens[token.Address] = token
+	}
+	for _, addr := range policy.Allowlist {
+		r.allow[common.HexToAddress(addr)] = struct{}{}
+	}
+	for _, addr := range policy.Denylist {
+		r.deny[common.HexToAddress(addr)] = struct{}{}
+	}
+
+	return r
+}
+
+// newTokenRegistryFromSymbols builds a registry from a symbol -> address map
+func newTokenRegistryFromSymbols(tokenAddresses map[string]string) *TokenRegistry {
+	tokens := make([]TokenInfo, 0, len(tokenAddresses))
+	for symbol, addr := range tokenAddresses {
+		tokens = append(tokens, TokenInfo{
+			Address: common.HexToAddress(addr),
+			Symbol:  symbol,
+		})
+	}
+	return NewTokenRegistry(nil, tokens, TokenPolicy{})
+}
+
+// Register adds or replaces the metadata of a token
+func (r *TokenRegistry) Register(token TokenInfo) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.tokens[token.Address] = token
+}
+
+// Lookup returns the registered or cached metadata of a token without RPC calls
+func (r *TokenRegistry) Lookup(addr common.Address) (TokenInfo, bool) {
+	r.mu.RLock()
+	token, ok := r.tokens[addr]
+	r.mu.RUnlock()
+	if ok {
+		return token, true
+	}
+	return r.resolved.Get(addr)
+}
+
+// Resolve returns the metadata of a token, querying the contract when it is
+// not yet known. Failed lookups are not retried until the retry interval passes.
+func (r *TokenRegistry) Resolve(ctx context.Context, addr common.Address, tokenType string) (TokenInfo, bool) {
+	if token, ok := r.Lookup(addr); ok {
+		return token, true
+	}
+
+	if r.rpcClient == nil {
+		return TokenInfo{}, false
+	}
+
+	if failedAt, failed := r.failed.Get(addr); failed && time.Since(failedAt) < r.retryInterval {
+		return TokenInfo{}, false
+	}
+
+	token, err := r.fetchTokenInfo(ctx, addr, tokenType)
+	if err != nil {
+		r.failed.Put(addr, time.Now())
+		return TokenInfo{}, false
+	}
+
+	r.resolved.Put(addr, token)
+	return token, true
+}
+
+// Allowed reports whether the token passes the allowlist/denylist policy
+func (r *TokenRegistry) Allowed(addr common.Address, resolved bool) bool {
+	if _, denied := r.deny[addr]; denied {
+		return false
+	}
+	if len(r.allow) > 0 {
+		_, allowed := r.allow[addr]
+		return allowed
+	}
+	if r.dropUnknown && !resolved {
+		return false
+	}
+	return true
+}
+
+// fetchTokenInfo queries symbol(), name() and decimals() of a token contract
+// in one batch request. Only symbol() is required; NFT contracts commonly
+// lack decimals().
+func (r *TokenRegistry) fetchTokenInfo(ctx context.Context, addr common.Address, tokenType string) (TokenInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.lookupTimeout)
+	defer cancel()
+
+	selectors := [][]byte{selectorSymbol, selectorName}
+	if tokenType == "" || tokenType == TokenTypeERC20 {
+		selectors = append(selectors, selectorDecimals)
+	}
+
+	results := make([]hexutil.Bytes, len(selectors))
+	batch := make([]rpc.BatchElem, len(selectors))
+	for i, selector := range selectors {
+		batch[i] = rpc.BatchElem{
+			Method: "eth_call",
+			Args:   []interface{}{map[string]interface{}{"to": addr, "data": hexutil.Bytes(selector)}, "latest"},
+			Result: &results[i],
+		}
+	}
+	if err := r.rpcClient.Client().BatchCallContext(ctx, batch); err != nil {
+		return TokenInfo{}, fmt.Errorf("failed to query token metadata: %w", err)
+	}
+
+	if batch[0].Error != nil {
+		return TokenInfo{}, fmt.Errorf("failed to call symbol(): %w", batch[0].Error)
+	}
+	symbol, err := decodeABIString(results[0])
+	if err != nil {
+		return TokenInfo{}, fmt.Errorf("failed to decode symbol(): %w", err)
+	}
+
+	token := TokenInfo{
+		Address: addr,
+		Symbol:  symbol,
+		Type:    tokenType,
+	}
+
+	if batch[1].Error == nil {
+		token.Name, _ = decodeABIString(results[1])
+	}
+
+	if len(batch) > 2 && batch[2].Error == nil && len(results[2]) >= 32 {
+		decimals := new(big.Int).SetBytes(results[2][:32])
+		if decimals.IsInt64() && decimals.Int64() <= 255 {
+			token.Decimals = int(decimals.Int64())
+			token.HasDecimals = true
+		}
+	}
+
+	return token, nil
+}
+
+// decodeABIString decodes a string return value. Some older tokens return
+// bytes32 instead of string, which is handled as well.
+func decodeABIString(data []byte) (string, error) {
+	if len(data) == 32 {
+		return strings.TrimSpace(string(bytes.TrimRight(data, "\x00"))), nil
+	}
+	if len(data) < 64 {
+		return "", fmt.Errorf("return data too short: %d bytes", len(data))
+	}
+
+	// Bounds are checked before any addition so that huge words from a
+	// malicious contract cannot overflow into a valid looking slice
+	size := uint64(len(data))
+	offset := new(big.Int).SetBytes(data[:32])
+	if !offset.IsUint64() || offset.Uint64() > size-32 {
+		return "", fmt.Errorf("invalid string offset")
+	}
+	start := offset.Uint64() + 32
+
+	length := new(big.Int).SetBytes(data[start-32 : start])
+	if !length.IsUint64() || length.Uint64() > size-start {
+		return "", fmt.Errorf("invalid string length")
+	}
+
+	return string(data[start : start+length.Uint64()]), nil
+}
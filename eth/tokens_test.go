@@ -0,0 +1,167 @@
+package eth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// abiString ABI-encodes a string return value
+func abiString(s string) string {
+	padded := (len(s) + 31) / 32 * 32
+	data := make([]byte, 64+padded)
+	data[31] = 32
+	data[63] = byte(len(s))
+	copy(data[64:], s)
+	return hexutil.Encode(data)
+}
+
+// abiUint ABI-encodes a small unsigned integer return value
+func abiUint(n byte) string {
+	data := make([]byte, 32)
+	data[31] = n
+	return hexutil.Encode(data)
+}
+
+func TestDecodeABIString(t *testing.T) {
+	bytes32 := make([]byte, 32)
+	copy(bytes32, "MKR")
+
+	tests := []struct {
+		name    string
+		data    string
+		want    string
+		wantErr bool
+	}{
+		{"string", abiString("USD Coin"), "USD Coin", false},
+		{"long string", abiString(strings.Repeat("x", 40)), strings.Repeat("x", 40), false},
+		{"bytes32", hexutil.Encode(bytes32), "MKR", false},
+		{"too short", "0x1234", "", true},
+		{"offset out of range", "0x" + strings.Repeat("00", 31) + "ff" + strings.Repeat("00", 32), "", true},
+		{"length out of range", "0x" + strings.Repeat("00", 31) + "20" + strings.Repeat("00", 31) + "ff", "", true},
+		{"oversized offset", "0x" + strings.Repeat("00", 24) + "7f" + strings.Repeat("ff", 7) + strings.Repeat("00", 32), "", true},
+		{"oversized length", "0x" + strings.Repeat("00", 31) + "20" + strings.Repeat("00", 24) + "7f" + strings.Repeat("ff", 7), "", true},
+		{"uint64 length", "0x" + strings.Repeat("00", 31) + "20" + strings.Repeat("00", 24) + strings.Repeat("ff", 8), "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeABIString(hexutil.MustDecode(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeABIString error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("decodeABIString = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenRegistryAllowed(t *testing.T) {
+	allowed := common.HexToAddress(testAddrA)
+	denied := common.HexToAddress(testAddrB)
+	other := common.HexToAddress(testAddrC)
+
+	tests := []struct {
+		name     string
+		policy   TokenPolicy
+		addr     common.Address
+		resolved bool
+		want     bool
+	}{
+		{"no policy", TokenPolicy{}, other, false, true},
+		{"denylist", TokenPolicy{Denylist: []string{denied.Hex()}}, denied, true, false},
+		{"allowlist member", TokenPolicy{Allowlist: []string{allowed.Hex()}}, allowed, false, true},
+		{"allowlist non-member", TokenPolicy{Allowlist: []string{allowed.Hex()}}, other, true, false},
+		{"denylist wins over allowlist", TokenPolicy{Allowlist: []string{denied.Hex()}, Denylist: []string{denied.Hex()}}, denied, true, false},
+		{"drop unresolved", TokenPolicy{DropUnresolved: true}, other, false, false},
+		{"keep resolved", TokenPolicy{DropUnresolved: true}, other, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := NewTokenRegistry(nil, nil, tt.policy)
+			if got := registry.Allowed(tt.addr, tt.resolved); got != tt.want {
+				t.Errorf("Allowed(%s, %v) = %v, want %v", tt.addr.Hex(), tt.resolved, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenRegistryResolve(t *testing.T) {
+	var mu sync.Mutex
+	failing := true
+	calls := 0
+	rpcClient := newFakeRPC(t, func(req rpcRequest) (interface{}, error) {
+		if req.Method != "eth_call" {
+			return nil, fmt.Errorf("unexpected method %s", req.Method)
+		}
+		var call struct {
+			Data string `json:"data"`
+		}
+		json.Unmarshal(req.Params[0], &call)
+
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if failing {
+			return nil, fmt.Errorf("execution reverted")
+		}
+		switch call.Data {
+		case "0x95d89b41":
+			return abiString("POINT"), nil
+		case "0x06fdde03":
+			return abiString("Loyalty Point"), nil
+		case "0x313ce567":
+			return abiUint(0), nil
+		}
+		return nil, fmt.Errorf("unexpected call data %s", call.Data)
+	})
+
+	callCount := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return calls
+	}
+
+	registry := NewTokenRegistry(rpcClient, nil, TokenPolicy{})
+	addr := common.HexToAddress(testAddrC)
+	ctx := context.Background()
+
+	if _, ok := registry.Resolve(ctx, addr, TokenTypeERC20); ok {
+		t.Fatal("Resolve succeeded although every call failed")
+	}
+	mu.Lock()
+	failing = false
+	mu.Unlock()
+	if _, ok := registry.Resolve(ctx, addr, TokenTypeERC20); ok || callCount() != 3 {
+		t.Fatalf("failed lookup was retried before the retry interval (%d calls)", callCount())
+	}
+
+	registry.retryInterval = time.Millisecond
+	time.Sleep(2 * time.Millisecond)
+	token, ok := registry.Resolve(ctx, addr, TokenTypeERC20)
+	if !ok {
+		t.Fatal("Resolve failed after the retry interval")
+	}
+	if token.Symbol != "POINT" || token.Name != "Loyalty Point" || token.Decimals != 0 || !token.HasDecimals {
+		t.Errorf("resolved token = %+v, want POINT with known zero decimals", token)
+	}
+	if got := token.decimals(ethDecimals); got != 0 {
+		t.Errorf("decimals of a zero-decimal token = %d, want 0", got)
+	}
+
+	if _, ok := registry.Resolve(ctx, addr, TokenTypeERC20); !ok || callCount() != 6 {
+		t.Errorf("resolved token was looked up again (%d calls)", callCount())
+	}
+	if _, ok := registry.Lookup(addr); !ok {
+		t.Error("resolved token is not cached")
+	}
+}
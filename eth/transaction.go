@@ -0,0 +1,111 @@
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/dawitel/alchemy-webhook/cache"
+)
+
+// TransactionHandler is a callback function for activities grouped by transaction
+type TransactionHandler func(ctx context.Context, tx ProcessedTransaction) error
+
+// WithTransactionHandler enables aggregated mode: ProcessActivities groups
+// the activities of a payload by transaction hash and delivers each group to
+// handler in a single call instead of to the activity subscribers.
+func WithTransactionHandler(handler TransactionHandler) ProcessorOption {
+	return func(p *Processor) {
+		p.txHandler = handler
+	}
+}
+
+// ProcessActivities processes the activities of one webhook payload. In
+// aggregated mode the events of each transaction are delivered together and
+// marked as processed only when the transaction handler succeeds; otherwise
+// every activity is processed on its own. Errors of individual activities or
+// transactions are joined, the remaining ones are still processed.
+func (p *Processor) ProcessActivities(ctx context.Context, activities []AlchemyActivity) error {
+	activities = slices.Clone(activities)
+	numberInternalTransfers(activities)
+
+	var errs []error
+	var events []ProcessedActivity
+	for _, activity := range activities {
+		prepared, err := p.prepare(ctx, activity)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("activity %s: %w", activity.Hash, err))
+			continue
+		}
+		events = append(events, prepared...)
+	}
+	events = p.unprocessed(ctx, events)
+	p.enrich(ctx, events)
+
+	if p.txHandler == nil {
+		for _, event := range events {
+			if err := p.deliver(ctx, event); err != nil {
+				errs = append(errs, fmt.Errorf("activity %s: %w", event.EventID, err))
+			}
+		}
+		return errors.Join(errs...)
+	}
+
+	var order []string
+	groups := make(map[string]*ProcessedTransaction)
+	for _, event := range events {
+		tx, ok := groups[event.TxHash]
+		if !ok {
+			tx = &ProcessedTransaction{
+				TxHash:      event.TxHash,
+				BlockNumber: event.BlockNumber,
+				BlockHash:   event.BlockHash,
+			}
+			groups[event.TxHash] = tx
+			order = append(order, event.TxHash)
+		}
+		if tx.BlockHash == "" {
+			tx.BlockHash = event.BlockHash
+		}
+		if tx.BlockTimestamp == 0 {
+			tx.BlockTimestamp = event.BlockTimestamp
+		}
+		if tx.Receipt == nil {
+			tx.Receipt = event.Receipt
+		}
+		tx.Activities = append(tx.Activities, event)
+	}
+
+	for _, txHash := range order {
+		if err := p.deliverTransaction(ctx, *groups[txHash]); err != nil {
+			errs = append(errs, fmt.Errorf("transaction %s: %w", txHash, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// deliverTransaction runs the transaction handler for the events of a
+// transaction that were not processed yet and marks them afterwards
+func (p *Processor) deliverTransaction(ctx context.Context, tx ProcessedTransaction) error {
+	if err := p.txHandler(ctx, tx); err != nil {
+		return fmt.Errorf("transaction handler error: %w", err)
+	}
+
+	if p.cache != nil {
+		keys := make([]string, len(tx.Activities))
+		for i, event := range tx.Activities {
+			keys[i] = event.EventID
+		}
+		if err := cache.AsBatch(p.cache).MarkProcessedBatch(ctx, keys, processedTTL); err != nil {
+			p.logger.Warn().Err(err).Str("hash", tx.TxHash).Msg("Failed to mark transaction as processed")
+		}
+	}
+
+	for _, event := range tx.Activities {
+		p.track(ctx, event)
+	}
+
+	return nil
+}
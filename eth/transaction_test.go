@@ -0,0 +1,115 @@
+package eth
+
+import (
+	"context"
+	"encoding/json"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dawitel/alchemy-webhook/cache"
+	"github.com/rs/zerolog"
+)
+
+func TestProcessActivitiesGroupsByTransaction(t *testing.T) {
+	const otherHash = "0000000000000000000000000000000000000000000000000000000000000001"
+	payload := `[
+		{"blockNum":"0x10","hash":"0x` + testHash + `","fromAddress":"0x` + testAddrA + `","toAddress":"0x` + testAddrB + `",
+			"value":1,"category":"external","rawContract":{"rawValue":"0xde0b6b3a7640000","decimals":18}},
+		{"blockNum":"0x10","hash":"0x` + otherHash + `","fromAddress":"0x` + testAddrB + `","toAddress":"0x` + testAddrA + `",
+			"value":2,"category":"external","rawContract":{"rawValue":"0x1bc16d674ec80000","decimals":18}},
+		{"blockNum":"0x10","hash":"0x` + testHash + `","fromAddress":"0x` + testAddrA + `","toAddress":"0x` + testAddrB + `",
+			"value":5,"category":"erc20","asset":"USDC","rawContract":{"rawValue":"0x4c4b40","address":"0x` + testAddrC + `","decimals":6},
+			"log":{"logIndex":"0x3","blockHash":"0xabc"}}
+	]`
+
+	var activities []AlchemyActivity
+	if err := json.Unmarshal([]byte(payload), &activities); err != nil {
+		t.Fatalf("failed to parse activities: %v", err)
+	}
+
+	var got []ProcessedTransaction
+	memoryCache := cache.NewMemoryCache(100, time.Minute, false)
+	defer memoryCache.Close()
+
+	processor := NewProcessor(zerolog.Nop(), memoryCache, nil, nil, "eth-mainnet",
+		WithTransactionHandler(func(ctx context.Context, tx ProcessedTransaction) error {
+			got = append(got, tx)
+			return nil
+		}),
+	)
+
+	if err := processor.ProcessActivities(context.Background(), activities); err != nil {
+		t.Fatalf("ProcessActivities returned error: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d transactions, want 2", len(got))
+	}
+	if got[0].TxHash != "0x"+testHash || len(got[0].Activities) != 2 {
+		t.Errorf("first transaction = %s with %d activities, want 0x%s with 2", got[0].TxHash, len(got[0].Activities), testHash)
+	}
+	if got[0].Activities[1].Currency != "USDC" || got[0].BlockHash != "0xabc" {
+		t.Errorf("unexpected grouped activity %+v", got[0])
+	}
+	if got[1].TxHash != "0x"+otherHash || len(got[1].Activities) != 1 {
+		t.Errorf("second transaction = %s with %d activities, want 0x%s with 1", got[1].TxHash, len(got[1].Activities), otherHash)
+	}
+
+	got = nil
+	if err := processor.ProcessActivities(context.Background(), activities); err != nil {
+		t.Fatalf("ProcessActivities returned error on redelivery: %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("redelivered payload produced %d transactions, want 0", len(got))
+	}
+}
+
+func TestProcessActivitiesSkipsEnrichmentOfProcessedEvents(t *testing.T) {
+	payload := `[{"blockNum":"0x10","hash":"0x` + testHash + `","fromAddress":"0x` + testAddrA + `","toAddress":"0x` + testAddrB + `",
+		"value":1,"category":"external","rawContract":{"rawValue":"0xde0b6b3a7640000","decimals":18}}]`
+	var activities []AlchemyActivity
+	if err := json.Unmarshal([]byte(payload), &activities); err != nil {
+		t.Fatalf("failed to parse activities: %v", err)
+	}
+
+	var calls atomic.Int32
+	chain := fakeChain(1000, 1700000000, 12)
+	rpcClient := newFakeRPC(t, func(req rpcRequest) (interface{}, error) {
+		calls.Add(1)
+		return chain(req)
+	})
+
+	for _, aggregated := range []bool{false, true} {
+		memoryCache := cache.NewMemoryCache(100, time.Minute, false)
+		defer memoryCache.Close()
+		calls.Store(0)
+
+		// A fresh processor per delivery, as on another replica, so the
+		// block time cache does not hide the lookups
+		var delivered int
+		for i := 0; i < 2; i++ {
+			opts := []ProcessorOption{WithBlockTimes(NewBlockTimes(rpcClient, 0, zerolog.Nop()))}
+			if aggregated {
+				opts = append(opts, WithTransactionHandler(func(ctx context.Context, tx ProcessedTransaction) error {
+					delivered += len(tx.Activities)
+					return nil
+				}))
+			}
+			processor := NewProcessor(zerolog.Nop(), memoryCache, nil, func(ctx context.Context, event ProcessedActivity) error {
+				delivered++
+				return nil
+			}, "eth-mainnet", opts...)
+
+			if err := processor.ProcessActivities(context.Background(), activities); err != nil {
+				t.Fatalf("ProcessActivities returned error: %v", err)
+			}
+		}
+
+		if delivered != 1 {
+			t.Errorf("aggregated=%v: delivered %d events, want 1", aggregated, delivered)
+		}
+		if got := calls.Load(); got != 1 {
+			t.Errorf("aggregated=%v: made %d RPC calls, want 1 for the first delivery only", aggregated, got)
+		}
+	}
+}
@@ -1,5 +1,11 @@
 package eth
 
+import (
+	"encoding/json"
+
+	"github.com/dawitel/alchemy-webhook/watch"
+)
+
 // AlchemyWebhookPayload represents the webhook payload from Alchemy
 type AlchemyWebhookPayload struct {
 	WebhookID string `json:"webhookId"`
@@ -14,18 +20,32 @@ type AlchemyWebhookPayload struct {
 
 // AlchemyActivity represents a single activity in the webhook payload
 type AlchemyActivity struct {
-	BlockNum         string              `json:"blockNum"`
-	Hash             string              `json:"hash"`
-	FromAddress      string              `json:"fromAddress"`
-	ToAddress        string              `json:"toAddress"`
-	Value            *float64            `json:"value,omitempty"`
-	ERC721TokenID    *string             `json:"erc721TokenId,omitempty"`
-	ERC1155Metadata  interface{}         `json:"erc1155Metadata,omitempty"`
-	Asset            string              `json:"asset,omitempty"`
-	Category         string              `json:"category"`
-	RawContract      *AlchemyRawContract `json:"rawContract,omitempty"`
-	TypeTraceAddress *string             `json:"typeTraceAddress,omitempty"`
-	Log              *AlchemyLog         `json:"log,omitempty"`
+	BlockNum         string                   `json:"blockNum"`
+	Hash             string                   `json:"hash"`
+	FromAddress      string                   `json:"fromAddress"`
+	ToAddress        string                   `json:"toAddress"`
+	Value            *json.Number             `json:"value,omitempty"`
+	ERC721TokenID    *string                  `json:"erc721TokenId,omitempty"`
+	ERC1155Metadata  []AlchemyERC1155Metadata `json:"erc1155Metadata,omitempty"`
+	Asset            string                   `json:"asset,omitempty"`
+	Category         string                   `json:"category"`
+	RawContract      *AlchemyRawContract      `json:"rawContract,omitempty"`
+	TypeTraceAddress *string                  `json:"typeTraceAddress,omitempty"`
+	Log              *AlchemyLog              `json:"log,omitempty"`
+	Metadata         *AlchemyTransferMetadata `json:"metadata,omitempty"`
+
+	occurrence int // Index among identical internal transfers of the transaction
+}
+
+// AlchemyTransferMetadata represents the block metadata of a transfer
+type AlchemyTransferMetadata struct {
+	BlockTimestamp string `json:"blockTimestamp"`
+}
+
+// AlchemyERC1155Metadata represents a single token ID and value of an ERC-1155 transfer
+type AlchemyERC1155Metadata struct {
+	TokenID string `json:"tokenId"`
+	Value   string `json:"value"`
 }
 
 // AlchemyRawContract represents raw contract data
@@ -105,14 +125,18 @@ type AlchemyWebhookAddressesResponse struct {
 
 // AlchemyAssetTransfer represents an asset transfer for backfill
 type AlchemyAssetTransfer struct {
-	BlockNum    string   `json:"blockNum"`
-	Hash        string   `json:"hash"`
-	From        string   `json:"from"`
-	To          string   `json:"to"`
-	Value       *float64 `json:"value"`
-	Asset       string   `json:"asset"`
-	Category    string   `json:"category"`
-	RawContract struct {
+	BlockNum        string                   `json:"blockNum"`
+	UniqueID        string                   `json:"uniqueId"`
+	Hash            string                   `json:"hash"`
+	From            string                   `json:"from"`
+	To              string                   `json:"to"`
+	Value           *json.Number             `json:"value"`
+	ERC721TokenID   *string                  `json:"erc721TokenId,omitempty"`
+	ERC1155Metadata []AlchemyERC1155Metadata `json:"erc1155Metadata,omitempty"`
+	Asset           string                   `json:"asset"`
+	Category        string                   `json:"category"`
+	Metadata        *AlchemyTransferMetadata `json:"metadata,omitempty"`
+	RawContract     struct {
 		Value   string `json:"value"`
 		Address string `json:"address"`
 		Decimal string `json:"decimal"`
@@ -127,13 +151,34 @@ type AlchemyAssetTransfersResponse struct {
 
 // ProcessedActivity represents a processed activity ready for callback
 type ProcessedActivity struct {
-	TxHash      string
-	FromAddress string
-	ToAddress   string
-	Value       string // BigInt as string
-	Currency    string
-	Category    string
-	BlockNumber uint64
-	Network     string
-	IsInternal  bool
+	EventID         string // Stable unique ID: hash + log index, trace address or category
+	TxHash          string
+	FromAddress     string
+	ToAddress       string
+	Value           string // BigInt as string
+	Currency        string
+	Decimals        int
+	Category        string
+	ContractAddress string
+	TokenID         string // ERC-721 or ERC-1155 token ID
+	BlockNumber     uint64
+	BlockHash       string
+	LogIndex        *uint64
+	TraceAddress    string
+	Network         string
+	IsInternal      bool
+	Direction       watch.Direction // Empty when no watched address set is configured
+	WatchedAddress  string          // Watched address the direction was derived from
+	BlockTimestamp  uint64          // Unix seconds, zero when unknown
+	Receipt         *TxReceipt      // Set by a ReceiptEnricher
+}
+
+// ProcessedTransaction groups the processed activities of one transaction
+type ProcessedTransaction struct {
+	TxHash         string
+	BlockNumber    uint64
+	BlockHash      string
+	BlockTimestamp uint64              // Unix seconds, zero when unknown
+	Receipt        *TxReceipt          // Set by a ReceiptEnricher
+	Activities     []ProcessedActivity // In payload order
 }
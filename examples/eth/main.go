@@ -56,6 +56,7 @@ func main() {
 		map[string]string{}, // token addresses
 		func(ctx context.Context, activity eth.ProcessedActivity) error {
 			logger.Info().
+				Str("event_id", activity.EventID).
 				Str("tx_hash", activity.TxHash).
 				Str("from", activity.FromAddress).
 				Str("to", activity.ToAddress).
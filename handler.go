@@ -17,6 +17,12 @@ type EthereumProcessor interface {
 	ProcessActivity(ctx context.Context, activity eth.AlchemyActivity) error
 }
 
+// EthereumBatchProcessor is implemented by Ethereum processors that handle
+// all activities of a payload at once, e.g. to group them by transaction
+type EthereumBatchProcessor interface {
+	ProcessActivities(ctx context.Context, activities []eth.AlchemyActivity) error
+}
+
 // SolanaProcessor interface for processing Solana transactions
 type SolanaProcessor interface {
 	ProcessTransaction(ctx context.Context, tx solana.AlchemySolanaTransaction, slot uint64) error
@@ -147,6 +153,13 @@ func (h *Handler) handleEthereumWebhook(ctx context.Context, body []byte) error
 		Int("activity_count", len(payload.Event.Activity)).
 		Msg("Processing Ethereum webhook activities")
 
+	if batch, ok := h.ethProcessor.(EthereumBatchProcessor); ok {
+		if err := batch.ProcessActivities(ctx, payload.Event.Activity); err != nil {
+			h.logger.Error().Err(err).Msg("Failed to process activities")
+		}
+		return nil
+	}
+
 	for _, activity := range payload.Event.Activity {
 		if err := h.ethProcessor.ProcessActivity(ctx, activity); err != nil {
 			h.logger.Error().Err(err).
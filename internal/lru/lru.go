@@ -0,0 +1,71 @@
+// Package lru provides a small concurrency-safe LRU cache shared by the
+// chain packages for receipts, token metadata and block times.
+package lru
+
+import (
+	"container/list"
+	"sync"
+)
+
+// Cache is a concurrency-safe LRU cache holding a bounded number of entries
+type Cache[K comparable, V any] struct {
+	mu      sync.Mutex
+	maxSize int
+	entries map[K]*list.Element
+	order   *list.List
+}
+
+// entry is a key and value stored in the LRU order list
+type entry[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+// New creates a cache holding up to maxSize entries
+func New[K comparable, V any](maxSize int) *Cache[K, V] {
+	return &Cache[K, V]{
+		maxSize: maxSize,
+		entries: make(map[K]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// Get returns the value of key and marks it as recently used
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*entry[K, V]).value, true
+}
+
+// Put stores a value, evicting the least recently used entry when full
+func (c *Cache[K, V]) Put(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		elem.Value.(*entry[K, V]).value = value
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&entry[K, V]{key: key, value: value})
+	if c.order.Len() > c.maxSize {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*entry[K, V]).key)
+	}
+}
+
+// Len returns the number of cached entries
+func (c *Cache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
@@ -0,0 +1,52 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLimiterBurstThenRate(t *testing.T) {
+	limiter := New(50, 2)
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		if err := limiter.Wait(ctx); err != nil {
+			t.Fatalf("Wait returned error: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Millisecond {
+		t.Errorf("burst took %v, want no wait", elapsed)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := limiter.Wait(ctx); err != nil {
+			t.Fatalf("Wait returned error: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("5 events at 50/s with burst 2 took %v, want at least 50ms", elapsed)
+	}
+}
+
+func TestLimiterWaitCancelled(t *testing.T) {
+	limiter := New(1, 1)
+	limiter.Wait(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := limiter.Wait(ctx); err == nil {
+		t.Error("Wait returned nil after the context expired")
+	}
+}
+
+func TestNilLimiterDoesNotWait(t *testing.T) {
+	var limiter *Limiter = New(0, 0)
+	if limiter != nil {
+		t.Fatal("New(0, 0) should return an unlimited nil limiter")
+	}
+	if err := limiter.Wait(context.Background()); err != nil {
+		t.Errorf("Wait returned error: %v", err)
+	}
+}
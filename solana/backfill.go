@@ -1,70 +1,87 @@
 package solana
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
-	"sync/atomic"
 	"time"
 
+	"github.com/dawitel/alchemy-webhook/backfill"
 	"github.com/dawitel/alchemy-webhook/cache"
+	"github.com/dawitel/alchemy-webhook/checkpoint"
 	"github.com/rs/zerolog"
 )
 
 // Backfill handles Solana historical transaction backfill
 type Backfill struct {
-	heliusAPIKey string
-	heliusURL    string
-	processor    *Processor
-	logger       zerolog.Logger
-	cache        cache.Cache
-	timeRange    time.Duration
-	batchSize    int
-	httpClient   *http.Client
-	backfilling  int32
+	source      Source
+	processor   *Processor
+	logger      zerolog.Logger
+	cache       cache.Cache
+	timeRange   time.Duration
+	batchSize   int
+	checkpoints checkpoint.Store
+	runner      backfill.Runner
 }
 
-// NewBackfill creates a new Solana backfill instance
+// BackfillOption configures a Backfill
+type BackfillOption func(*Backfill)
+
+// WithCheckpoints makes backfill resumable. The slot and signature of the
+// newest transaction backfilled for each address are recorded, and the next
+// run fetches every transaction after it, even those older than the time
+// range, so a scheduled backfill covers only new transactions.
+func WithCheckpoints(store checkpoint.Store) BackfillOption {
+	return func(b *Backfill) {
+		b.checkpoints = store
+	}
+}
+
+// NewBackfill creates a new Solana backfill instance fetching history from
+// source, e.g. an RPCSource or a HeliusSource
 func NewBackfill(
-	heliusAPIKey string,
-	heliusURL string,
+	source Source,
 	processor *Processor,
 	logger zerolog.Logger,
 	cache cache.Cache,
 	timeRange time.Duration,
 	batchSize int,
-	httpClient *http.Client,
+	opts ...BackfillOption,
 ) *Backfill {
-	if httpClient == nil {
-		httpClient = &http.Client{Timeout: 30 * time.Second}
+	b := &Backfill{
+		source:    source,
+		processor: processor,
+		logger:    logger,
+		cache:     cache,
+		timeRange: timeRange,
+		batchSize: batchSize,
 	}
-
-	return &Backfill{
-		heliusAPIKey: heliusAPIKey,
-		heliusURL:    heliusURL,
-		processor:    processor,
-		logger:       logger,
-		cache:        cache,
-		timeRange:    timeRange,
-		batchSize:    batchSize,
-		httpClient:   httpClient,
+	for _, opt := range opts {
+		opt(b)
 	}
+	return b
 }
 
-// Backfill performs backfill for the given addresses
-func (b *Backfill) Backfill(ctx context.Context, addresses []string) error {
-	if !atomic.CompareAndSwapInt32(&b.backfilling, 0, 1) {
-		b.logger.Debug().Msg("Backfill already in progress, skipping")
-		return nil
+// Backfill starts a backfill of the given addresses and returns its job.
+// While another backfill runs, it returns that job with
+// backfill.ErrInProgress.
+func (b *Backfill) Backfill(ctx context.Context, addresses []string) (*backfill.Job, error) {
+	if b.source == nil {
+		return nil, fmt.Errorf("backfill source not configured")
 	}
-	defer atomic.StoreInt32(&b.backfilling, 0)
 
-	if b.heliusAPIKey == "" {
-		return fmt.Errorf("Helius API key not configured")
-	}
+	return b.runner.Start(ctx, func(ctx context.Context, job *backfill.Job) error {
+		return b.run(ctx, job, addresses)
+	})
+}
+
+// Job returns the last backfill job started, nil if none
+func (b *Backfill) Job() *backfill.Job {
+	return b.runner.Current()
+}
+
+// run performs a backfill, reporting its progress to job
+func (b *Backfill) run(ctx context.Context, job *backfill.Job, addresses []string) error {
+	job.SetAddresses(len(addresses))
 
 	if len(addresses) == 0 {
 		b.logger.Debug().Msg("No addresses to backfill")
@@ -81,6 +98,7 @@ func (b *Backfill) Backfill(ctx context.Context, addresses []string) error {
 
 	processedCount := 0
 	skippedCount := 0
+	failedCount := 0
 
 	for _, address := range addresses {
 		select {
@@ -89,50 +107,84 @@ func (b *Backfill) Backfill(ctx context.Context, addresses []string) error {
 		default:
 		}
 
-		transactions, err := b.getTransactionsForAddress(ctx, address, fromTime, toTime)
+		after, err := b.lastPosition(ctx, address)
+		if err != nil {
+			return err
+		}
+
+		// A checkpoint bounds the start on its own, so an outage longer
+		// than the time range leaves no gap
+		start := fromTime
+		if after.Slot > 0 {
+			start = 0
+		}
+
+		transactions, err := b.source.Transactions(ctx, address, start, toTime, after)
 		if err != nil {
 			b.logger.Warn().
 				Err(err).
 				Str("address", address).
 				Msg("Failed to get transactions, skipping address")
-			time.Sleep(2 * time.Second)
+			job.AddressError(address, err)
+			job.CompleteAddresses(1)
 			continue
 		}
 
-		for _, tx := range transactions {
+		failed := false
+		for start := 0; start < len(transactions); start += b.pageSize() {
 			select {
 			case <-ctx.Done():
 				return ctx.Err()
 			default:
 			}
 
-			if b.cache != nil {
-				processed, err := b.cache.IsProcessed(ctx, tx.Signature)
-				if err == nil && processed {
+			end := start + b.pageSize()
+			if end > len(transactions) {
+				end = len(transactions)
+			}
+			page := transactions[start:end]
+
+			signatures := make([]string, len(page))
+			for i, tx := range page {
+				signatures[i] = tx.signature()
+			}
+			processed := b.checkProcessed(ctx, signatures)
+
+			for i, tx := range page {
+				if processed[i] {
 					skippedCount++
+					job.Count(0, 1, 0)
 					continue
 				}
-			}
 
-			alchemyTx := b.convertToAlchemyTx(tx)
-			if alchemyTx != nil {
-				if err := b.processor.ProcessTransaction(ctx, *alchemyTx, uint64(tx.Slot)); err != nil {
-					b.logger.Warn().
-						Err(err).
-						Str("signature", tx.Signature).
-						Msg("Failed to process historical transaction")
-					continue
+				alchemyTx := convertToAlchemyTx(tx)
+				if alchemyTx != nil {
+					if err := b.processor.processTransaction(ctx, *alchemyTx, tx.Slot, tx.blockTime()); err != nil {
+						b.logger.Warn().
+							Err(err).
+							Str("signature", alchemyTx.Signature).
+							Msg("Failed to process historical transaction")
+						failed = true
+						failedCount++
+						job.Count(0, 0, 1)
+						continue
+					}
+					processedCount++
+					job.Count(1, 0, 0)
 				}
-				processedCount++
 			}
 		}
 
-		time.Sleep(1 * time.Second)
+		if !failed && ctx.Err() == nil {
+			b.savePosition(ctx, address, transactions)
+		}
+		job.CompleteAddresses(1)
 	}
 
 	b.logger.Info().
 		Int("processed", processedCount).
 		Int("skipped", skippedCount).
+		Int("failed", failedCount).
 		Int64("from_time", fromTime).
 		Int64("to_time", toTime).
 		Msg("Solana historical deposit backfill completed")
@@ -140,204 +192,74 @@ func (b *Backfill) Backfill(ctx context.Context, addresses []string) error {
 	return nil
 }
 
-// getTransactionsForAddress fetches transactions for an address using Helius RPC
-func (b *Backfill) getTransactionsForAddress(ctx context.Context, address string, fromTime, toTime int64) ([]ProcessedTransaction, error) {
-	url := fmt.Sprintf("%s?api-key=%s", b.heliusURL, b.heliusAPIKey)
-
-	reqBody := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"id":      "1",
-		"method":  "getTransactionsForAddress",
-		"params": []interface{}{
-			address,
-			map[string]interface{}{
-				"transactionDetails": "full",
-				"limit":              100,
-				"sortOrder":          "desc",
-				"commitment":         "finalized",
-				"encoding":           "jsonParsed",
-				"filters": map[string]interface{}{
-					"blockTime": map[string]interface{}{
-						"gte": fromTime,
-						"lte": toTime,
-					},
-					"status": "succeeded",
-				},
-			},
-		},
-	}
-
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+// pageSize returns how many transactions are checked against the cache at once
+func (b *Backfill) pageSize() int {
+	if b.batchSize <= 0 {
+		return 100
 	}
+	return b.batchSize
+}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+// checkProcessed looks up a page of signatures in one batch call.
+// On cache errors every signature is reported as unprocessed.
+func (b *Backfill) checkProcessed(ctx context.Context, signatures []string) []bool {
+	if b.cache == nil {
+		return make([]bool, len(signatures))
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := b.httpClient.Do(req)
+	processed, err := cache.AsBatch(b.cache).IsProcessedBatch(ctx, signatures)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get transactions: %w", err)
+		b.logger.Warn().
+			Err(err).
+			Int("signature_count", len(signatures)).
+			Msg("Failed to check processed transactions, continuing")
+		return make([]bool, len(signatures))
 	}
-	defer resp.Body.Close()
+	return processed
+}
 
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("failed to get transactions: status %d, body: %s", resp.StatusCode, string(bodyBytes))
+// lastPosition returns the newest transaction the address was backfilled
+// up to, the zero Position if none
+func (b *Backfill) lastPosition(ctx context.Context, address string) (Position, error) {
+	if b.checkpoints == nil {
+		return Position{}, nil
 	}
 
-	bodyBytes, err := io.ReadAll(resp.Body)
+	cp, ok, err := b.checkpoints.Get(ctx, b.checkpointKey(address))
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return Position{}, fmt.Errorf("failed to read checkpoint: %w", err)
 	}
-
-	var rpcResp struct {
-		JSONRPC string `json:"jsonrpc"`
-		ID      string `json:"id"`
-		Result  *struct {
-			Data            []map[string]interface{} `json:"data"`
-			PaginationToken *string                  `json:"paginationToken"`
-		} `json:"result"`
-		Error *struct {
-			Code    int    `json:"code"`
-			Message string `json:"message"`
-		} `json:"error"`
-	}
-
-	if err := json.Unmarshal(bodyBytes, &rpcResp); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
-	}
-
-	if rpcResp.Error != nil {
-		return nil, fmt.Errorf("RPC error: %s (code: %d)", rpcResp.Error.Message, rpcResp.Error.Code)
+	if !ok {
+		return Position{}, nil
 	}
-
-	if rpcResp.Result == nil {
-		return nil, fmt.Errorf("empty result in RPC response")
-	}
-
-	var signatures []string
-	for _, txData := range rpcResp.Result.Data {
-		var blockTime int64
-		var found bool
-
-		if bt, ok := txData["blockTime"].(float64); ok {
-			blockTime = int64(bt)
-			found = true
-		} else if bt, ok := txData["blockTime"].(int64); ok {
-			blockTime = bt
-			found = true
-		} else if bt, ok := txData["blockTime"].(json.Number); ok {
-			btInt, err := bt.Int64()
-			if err != nil {
-				continue
-			}
-			blockTime = btInt
-			found = true
-		}
-
-		if !found || blockTime < fromTime || blockTime > toTime {
-			continue
-		}
-
-		var sig string
-		if transactionObj, ok := txData["transaction"].(map[string]interface{}); ok {
-			if sigs, ok := transactionObj["signatures"].([]interface{}); ok && len(sigs) > 0 {
-				if s, ok := sigs[0].(string); ok {
-					sig = s
-				}
-			}
-		} else if s, ok := txData["signature"].(string); ok {
-			sig = s
-		}
-
-		if sig != "" {
-			signatures = append(signatures, sig)
-		}
-	}
-
-	if len(signatures) == 0 {
-		return nil, nil
-	}
-
-	enhancedTxs, err := b.getEnhancedTransactions(ctx, signatures)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get enhanced transactions: %w", err)
-	}
-
-	return enhancedTxs, nil
+	return Position{Slot: cp.Height, Signature: cp.Cursor}, nil
 }
 
-// getEnhancedTransactions fetches enhanced transaction details
-func (b *Backfill) getEnhancedTransactions(ctx context.Context, signatures []string) ([]ProcessedTransaction, error) {
-	if len(signatures) == 0 {
-		return nil, nil
+// savePosition records the slot and signature of the newest backfilled
+// transaction; transactions are ordered oldest first
+func (b *Backfill) savePosition(ctx context.Context, address string, transactions []RPCTransaction) {
+	if b.checkpoints == nil || len(transactions) == 0 {
+		return
 	}
 
-	batchSize := 100
-	var allTransactions []ProcessedTransaction
-
-	for i := 0; i < len(signatures); i += batchSize {
-		end := i + batchSize
-		if end > len(signatures) {
-			end = len(signatures)
-		}
-		batch := signatures[i:end]
-
-		url := fmt.Sprintf("https://api-mainnet.helius-rpc.com/v0/transactions?api-key=%s", b.heliusAPIKey)
-		reqBody := map[string]interface{}{
-			"transactions": batch,
-		}
-
-		jsonData, err := json.Marshal(reqBody)
-		if err != nil {
-			return nil, fmt.Errorf("failed to marshal request: %w", err)
-		}
-
-		req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
-		if err != nil {
-			return nil, fmt.Errorf("failed to create request: %w", err)
-		}
-
-		req.Header.Set("Content-Type", "application/json")
-
-		resp, err := b.httpClient.Do(req)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get enhanced transactions: %w", err)
-		}
-		defer resp.Body.Close()
-
-		if resp.StatusCode != http.StatusOK {
-			bodyBytes, _ := io.ReadAll(resp.Body)
-			return nil, fmt.Errorf("failed to get enhanced transactions: status %d, body: %s", resp.StatusCode, string(bodyBytes))
-		}
-
-		var transactions []map[string]interface{}
-		if err := json.NewDecoder(resp.Body).Decode(&transactions); err != nil {
-			return nil, fmt.Errorf("failed to decode enhanced transactions response: %w", err)
-		}
-
-		for _, tx := range transactions {
-			if sig, ok := tx["signature"].(string); ok {
-				processedTx := ProcessedTransaction{
-					Signature: sig,
-					Slot:      0,
-					Timestamp: time.Now().Unix(),
-				}
-				allTransactions = append(allTransactions, processedTx)
-			}
-		}
+	newest := transactions[len(transactions)-1]
+	if newest.Slot == 0 {
+		return
 	}
 
-	return allTransactions, nil
+	key := b.checkpointKey(address)
+	cp := checkpoint.Checkpoint{Key: key, Height: newest.Slot, Cursor: newest.signature(), UpdatedAt: time.Now()}
+	if err := b.checkpoints.Put(ctx, cp); err != nil {
+		b.logger.Warn().Err(err).Str("key", key).Msg("Failed to save backfill checkpoint")
+	}
 }
 
-func (b *Backfill) convertToAlchemyTx(tx ProcessedTransaction) *AlchemySolanaTransaction {
-	return &AlchemySolanaTransaction{
-		Signature: tx.Signature,
+// checkpointKey returns the checkpoint key of an address. Solana backfill
+// fetches every transaction involving the address, recorded as incoming.
+func (b *Backfill) checkpointKey(address string) string {
+	chain := "sol"
+	if b.processor != nil && b.processor.chainID != "" {
+		chain = b.processor.chainID
 	}
+	return checkpoint.Key(chain, "incoming", address)
 }
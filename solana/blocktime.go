@@ -0,0 +1,77 @@
+package solana
+
+import (
+	"context"
+
+	"github.com/dawitel/alchemy-webhook/internal/lru"
+	"github.com/rs/zerolog"
+)
+
+const defaultBlockTimeCacheSize = 10000
+
+// WithBlockTimes looks up the block time of webhook transactions, whose
+// payload does not carry one
+func WithBlockTimes(blockTimes *BlockTimes) ProcessorOption {
+	return func(p *Processor) {
+		p.blockTimes = blockTimes
+	}
+}
+
+// BlockTimes looks up slot times with getBlockTime and caches them by slot
+type BlockTimes struct {
+	rpcClient *RPCClient
+	logger    zerolog.Logger
+	times     *lru.Cache[uint64, int64]
+}
+
+// NewBlockTimes creates a block time lookup caching up to cacheSize slots.
+// A cacheSize <= 0 uses the default.
+func NewBlockTimes(rpcClient *RPCClient, cacheSize int, logger zerolog.Logger) *BlockTimes {
+	if cacheSize <= 0 {
+		cacheSize = defaultBlockTimeCacheSize
+	}
+
+	return &BlockTimes{
+		rpcClient: rpcClient,
+		logger:    logger,
+		times:     lru.New[uint64, int64](cacheSize),
+	}
+}
+
+// Lookup returns the block time of slot in Unix seconds
+func (b *BlockTimes) Lookup(ctx context.Context, slot uint64) (int64, error) {
+	if blockTime, ok := b.times.Get(slot); ok {
+		return blockTime, nil
+	}
+
+	blockTime, err := b.rpcClient.GetBlockTime(ctx, slot)
+	if err != nil {
+		return 0, err
+	}
+
+	b.times.Put(slot, blockTime)
+	return blockTime, nil
+}
+
+// blockTime returns known when the caller already has the block time, such
+// as backfill reading it from getTransaction, otherwise the result of the
+// block time lookup, or zero when neither is available
+func (p *Processor) blockTime(ctx context.Context, signature string, slot uint64, known int64) int64 {
+	if known > 0 {
+		return known
+	}
+	if p.blockTimes == nil || slot == 0 {
+		return 0
+	}
+
+	blockTime, err := p.blockTimes.Lookup(ctx, slot)
+	if err != nil {
+		p.logger.Warn().
+			Err(err).
+			Str("signature", signature).
+			Uint64("slot", slot).
+			Msg("Failed to look up block time")
+		return 0
+	}
+	return blockTime
+}
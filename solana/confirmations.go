@@ -0,0 +1,287 @@
+package solana
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dawitel/alchemy-webhook/confirmation"
+	"github.com/rs/zerolog"
+)
+
+const (
+	defaultConfirmationPollInterval = 2 * time.Second
+	// maxSignatureStatuses is the getSignatureStatuses limit per request
+	maxSignatureStatuses = 256
+)
+
+// ConfirmationEvent is emitted when a processed transaction reaches a
+// confirmation threshold or vanishes from the chain
+type ConfirmationEvent struct {
+	Transaction        ProcessedTransaction
+	Confirmations      uint64 // Slots elapsed since inclusion, counting the inclusion slot
+	Threshold          uint64 // Threshold that was reached, zero when Dropped
+	ConfirmationStatus string // Commitment reported by the node
+	Dropped            bool   // Signature is no longer known to the node
+}
+
+// ConfirmationHandler is a callback function for confirmation events
+type ConfirmationHandler func(ctx context.Context, event ConfirmationEvent) error
+
+// WithConfirmationTracker records every delivered transaction in tracker
+func WithConfirmationTracker(tracker *ConfirmationTracker) ProcessorOption {
+	return func(p *Processor) {
+		p.confirmations = tracker
+	}
+}
+
+// ConfirmationTracker polls the current slot and reports processed
+// transactions as they reach configured confirmation thresholds, measured in
+// slots. Before a threshold is reported the signature status is fetched again
+// to catch transactions that were dropped with their fork.
+type ConfirmationTracker struct {
+	rpcClient    *RPCClient
+	store        confirmation.Store
+	thresholds   []uint64
+	commitment   string
+	pollInterval time.Duration
+	handler      ConfirmationHandler
+	logger       zerolog.Logger
+	mu           sync.Mutex
+	cancel       context.CancelFunc
+	done         chan struct{}
+}
+
+// NewConfirmationTracker creates a new confirmation tracker.
+// A nil store defaults to an in-memory store and an empty commitment to confirmed.
+func NewConfirmationTracker(
+	rpcClient *RPCClient,
+	store confirmation.Store,
+	thresholds []uint64,
+	commitment string,
+	pollInterval time.Duration,
+	handler ConfirmationHandler,
+	logger zerolog.Logger,
+) *ConfirmationTracker {
+	if store == nil {
+		store = confirmation.NewMemoryStore()
+	}
+	if commitment == "" {
+		commitment = CommitmentConfirmed
+	}
+	if pollInterval <= 0 {
+		pollInterval = defaultConfirmationPollInterval
+	}
+
+	return &ConfirmationTracker{
+		rpcClient:    rpcClient,
+		store:        store,
+		thresholds:   confirmation.NormalizeThresholds(thresholds),
+		commitment:   commitment,
+		pollInterval: pollInterval,
+		handler:      handler,
+		logger:       logger,
+	}
+}
+
+// Track records a processed transaction awaiting confirmations
+func (t *ConfirmationTracker) Track(ctx context.Context, tx ProcessedTransaction) error {
+	if len(t.thresholds) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(tx)
+	if err != nil {
+		return fmt.Errorf("failed to encode transaction: %w", err)
+	}
+
+	return t.store.Put(ctx, confirmation.Record{
+		ID:        tx.Signature,
+		TxHash:    tx.Signature,
+		Height:    tx.Slot,
+		Payload:   payload,
+		CreatedAt: time.Now(),
+	})
+}
+
+// Start begins polling in the background until Stop is called or ctx is done
+func (t *ConfirmationTracker) Start(ctx context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.rpcClient == nil {
+		return fmt.Errorf("RPC client not available")
+	}
+	if t.cancel != nil {
+		return fmt.Errorf("confirmation tracker already started")
+	}
+
+	ctx, t.cancel = context.WithCancel(ctx)
+	t.done = make(chan struct{})
+
+	go func() {
+		defer close(t.done)
+
+		ticker := time.NewTicker(t.pollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := t.Poll(ctx); err != nil && ctx.Err() == nil {
+					t.logger.Warn().Err(err).Msg("Confirmation poll failed")
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Stop stops background polling and waits for the current poll to finish
+func (t *ConfirmationTracker) Stop() {
+	t.mu.Lock()
+	cancel, done := t.cancel, t.done
+	t.cancel = nil
+	t.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+}
+
+// Poll checks all pending records against the current slot once
+func (t *ConfirmationTracker) Poll(ctx context.Context) error {
+	slot, err := t.rpcClient.GetSlot(ctx, t.commitment)
+	if err != nil {
+		return fmt.Errorf("failed to get current slot: %w", err)
+	}
+
+	records, err := t.store.List(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list pending confirmations: %w", err)
+	}
+
+	var due []confirmation.Record
+	for _, record := range records {
+		if record.Reached >= len(t.thresholds) {
+			if err := t.store.Delete(ctx, record.ID); err != nil {
+				t.logger.Warn().Err(err).Str("signature", record.ID).Msg("Failed to delete confirmation record")
+			}
+			continue
+		}
+		if slot >= record.Height && slot-record.Height+1 >= t.thresholds[record.Reached] {
+			due = append(due, record)
+		}
+	}
+
+	for start := 0; start < len(due); start += maxSignatureStatuses {
+		end := min(start+maxSignatureStatuses, len(due))
+		batch := due[start:end]
+
+		signatures := make([]string, len(batch))
+		for i, record := range batch {
+			signatures[i] = record.TxHash
+		}
+
+		statuses, err := t.rpcClient.GetSignatureStatuses(ctx, signatures)
+		if err != nil {
+			return fmt.Errorf("failed to get signature statuses: %w", err)
+		}
+
+		for i, record := range batch {
+			if err := t.check(ctx, slot, record, statuses[i]); err != nil {
+				t.logger.Warn().
+					Err(err).
+					Str("signature", record.ID).
+					Msg("Failed to check confirmations")
+			}
+		}
+	}
+
+	return nil
+}
+
+// check reports the thresholds a record has reached since the last poll. A
+// transaction is reported dropped once its status is missing on
+// confirmation.DropAfterMisses consecutive checks.
+func (t *ConfirmationTracker) check(ctx context.Context, slot uint64, record confirmation.Record, status *SignatureStatus) error {
+	var tx ProcessedTransaction
+	if err := json.Unmarshal(record.Payload, &tx); err != nil {
+		return fmt.Errorf("failed to decode transaction: %w", err)
+	}
+
+	if status == nil {
+		record.Misses++
+		if record.Misses < confirmation.DropAfterMisses {
+			t.logger.Debug().
+				Str("signature", record.ID).
+				Int("misses", record.Misses).
+				Msg("Signature status not found, checking again")
+			return t.store.Put(ctx, record)
+		}
+
+		t.logger.Warn().
+			Str("signature", record.ID).
+			Uint64("slot", record.Height).
+			Msg("Transaction vanished before reaching confirmations")
+		if err := t.emit(ctx, ConfirmationEvent{Transaction: tx, Dropped: true}); err != nil {
+			return err
+		}
+		return t.store.Delete(ctx, record.ID)
+	}
+
+	record.Misses = 0
+
+	// The transaction landed in another slot on the surviving fork; count from there
+	if status.Slot != 0 && status.Slot != record.Height {
+		record.Height = status.Slot
+		tx.Slot = status.Slot
+		if payload, err := json.Marshal(tx); err == nil {
+			record.Payload = payload
+		}
+		if slot < record.Height {
+			return t.store.Put(ctx, record)
+		}
+	}
+
+	confirmations := slot - record.Height + 1
+	for record.Reached < len(t.thresholds) && confirmations >= t.thresholds[record.Reached] {
+		event := ConfirmationEvent{
+			Transaction:        tx,
+			Confirmations:      confirmations,
+			Threshold:          t.thresholds[record.Reached],
+			ConfirmationStatus: status.ConfirmationStatus,
+		}
+		if err := t.emit(ctx, event); err != nil {
+			// Keep the thresholds reached so far so only this one is retried
+			if putErr := t.store.Put(ctx, record); putErr != nil {
+				return errors.Join(err, fmt.Errorf("failed to save confirmation record: %w", putErr))
+			}
+			return err
+		}
+		record.Reached++
+	}
+
+	if record.Reached >= len(t.thresholds) {
+		return t.store.Delete(ctx, record.ID)
+	}
+	return t.store.Put(ctx, record)
+}
+
+// emit calls the confirmation handler
+func (t *ConfirmationTracker) emit(ctx context.Context, event ConfirmationEvent) error {
+	if t.handler == nil {
+		return nil
+	}
+	if err := t.handler(ctx, event); err != nil {
+		return fmt.Errorf("confirmation handler error: %w", err)
+	}
+	return nil
+}
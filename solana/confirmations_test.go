@@ -0,0 +1,154 @@
+package solana
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/dawitel/alchemy-webhook/confirmation"
+	"github.com/rs/zerolog"
+)
+
+// fakeSlots is a Solana JSON-RPC server answering getSlot and
+// getSignatureStatuses from a current slot and the slot a signature landed
+// in, zero when the node does not know it
+type fakeSlots struct {
+	mu      sync.Mutex
+	current uint64
+	landed  uint64
+}
+
+func (f *fakeSlots) set(current, landed uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.current, f.landed = current, landed
+}
+
+// newFakeSlots starts the server and returns an RPC client connected to it
+func newFakeSlots(t *testing.T) (*fakeSlots, *RPCClient) {
+	t.Helper()
+
+	node := &fakeSlots{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+			return
+		}
+
+		node.mu.Lock()
+		defer node.mu.Unlock()
+
+		var result interface{}
+		switch req.Method {
+		case "getSlot":
+			result = node.current
+		case "getSignatureStatuses":
+			var status interface{}
+			if node.landed != 0 {
+				status = map[string]interface{}{"slot": node.landed, "confirmationStatus": "confirmed", "err": nil}
+			}
+			result = map[string]interface{}{"value": []interface{}{status}}
+		default:
+			t.Errorf("unexpected method %s", req.Method)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": result})
+	}))
+	t.Cleanup(server.Close)
+
+	return node, NewRPCClient(server.URL, nil)
+}
+
+func TestConfirmationTrackerPoll(t *testing.T) {
+	type step struct {
+		current, landed uint64
+		want            string // Reported events, e.g. "1@100" for threshold 1 at slot 100, or "dropped"
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{"thresholds", []step{{100, 100, "1@100"}, {101, 100, ""}, {131, 100, "32@100"}, {140, 100, ""}}},
+		{"fork", []step{{100, 100, "1@100"}, {103, 102, ""}, {133, 102, "32@102"}}},
+		{"dropped", []step{{100, 0, ""}, {101, 0, ""}, {102, 0, "dropped"}, {140, 100, ""}}},
+		{"miss then found", []step{{100, 0, ""}, {101, 0, ""}, {102, 100, "1@100"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node, rpcClient := newFakeSlots(t)
+
+			var got []string
+			tracker := NewConfirmationTracker(rpcClient, nil, []uint64{1, 32}, "", 0, func(ctx context.Context, event ConfirmationEvent) error {
+				if event.Dropped {
+					got = append(got, "dropped")
+				} else {
+					got = append(got, fmt.Sprintf("%d@%d", event.Threshold, event.Transaction.Slot))
+				}
+				return nil
+			}, zerolog.Nop())
+
+			if err := tracker.Track(context.Background(), ProcessedTransaction{Signature: testSignature(1), Slot: 100}); err != nil {
+				t.Fatalf("Track returned error: %v", err)
+			}
+
+			for i, step := range tt.steps {
+				node.set(step.current, step.landed)
+				got = nil
+				if err := tracker.Poll(context.Background()); err != nil {
+					t.Fatalf("poll %d returned error: %v", i, err)
+				}
+				if strings.Join(got, " ") != step.want {
+					t.Errorf("poll %d at slot %d reported %q, want %q", i, step.current, strings.Join(got, " "), step.want)
+				}
+			}
+		})
+	}
+}
+
+// failingStore is a confirmation store whose Put fails once err is set
+type failingStore struct {
+	*confirmation.MemoryStore
+	err error
+}
+
+func (s *failingStore) Put(ctx context.Context, record confirmation.Record) error {
+	if s.err != nil {
+		return s.err
+	}
+	return s.MemoryStore.Put(ctx, record)
+}
+
+func TestConfirmationTrackerReportsStoreFailure(t *testing.T) {
+	_, rpcClient := newFakeSlots(t)
+
+	handlerErr := errors.New("handler down")
+	store := &failingStore{MemoryStore: confirmation.NewMemoryStore()}
+	tracker := NewConfirmationTracker(rpcClient, store, []uint64{1}, "", 0, func(ctx context.Context, event ConfirmationEvent) error {
+		return handlerErr
+	}, zerolog.Nop())
+
+	if err := tracker.Track(context.Background(), ProcessedTransaction{Signature: testSignature(1), Slot: 100}); err != nil {
+		t.Fatalf("Track returned error: %v", err)
+	}
+	records, err := store.List(context.Background())
+	if err != nil || len(records) != 1 {
+		t.Fatalf("List = %v, %v, want one record", records, err)
+	}
+
+	store.err = errors.New("store down")
+	status := &SignatureStatus{Slot: 100, ConfirmationStatus: "confirmed"}
+	err = tracker.check(context.Background(), 100, records[0], status)
+	if !errors.Is(err, handlerErr) || !errors.Is(err, store.err) {
+		t.Errorf("check error = %v, want both the handler and the store error", err)
+	}
+}
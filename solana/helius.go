@@ -0,0 +1,177 @@
+package solana
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/dawitel/alchemy-webhook/ratelimit"
+)
+
+// DefaultHeliusURL is the Helius mainnet RPC endpoint
+const DefaultHeliusURL = "https://mainnet.helius-rpc.com"
+
+// HeliusSource is a Source built on the Helius-only
+// getTransactionsForAddress method, which filters by time and slot on the
+// server and returns full transactions in one call per page
+type HeliusSource struct {
+	apiKey     string
+	url        string
+	httpClient *http.Client
+	limiter    *ratelimit.Limiter
+}
+
+// NewHeliusSource creates a Helius source. An empty url uses
+// DefaultHeliusURL. Every page request waits for limiter; a nil limiter does
+// not limit.
+func NewHeliusSource(apiKey, url string, httpClient *http.Client, limiter *ratelimit.Limiter) *HeliusSource {
+	if url == "" {
+		url = DefaultHeliusURL
+	}
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	return &HeliusSource{
+		apiKey:     apiKey,
+		url:        url,
+		httpClient: httpClient,
+		limiter:    limiter,
+	}
+}
+
+// Transactions fetches the full transactions of address with Helius
+// getTransactionsForAddress, following every page. Transactions are
+// filtered by the slot of after; slots are final, so every transaction of
+// that slot was backfilled with it.
+func (s *HeliusSource) Transactions(ctx context.Context, address string, fromTime, toTime int64, after Position) ([]RPCTransaction, error) {
+	var transactions []RPCTransaction
+	var paginationToken *string
+	for {
+		page, next, err := s.getTransactionsPage(ctx, address, fromTime, toTime, after.Slot, paginationToken)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, page...)
+		if next == nil || *next == "" {
+			break
+		}
+		if paginationToken != nil && *next == *paginationToken {
+			return nil, fmt.Errorf("pagination token %s returned twice", *next)
+		}
+		paginationToken = next
+	}
+
+	return transactions, nil
+}
+
+// getTransactionsPage fetches one page of getTransactionsForAddress in
+// ascending order and returns its transactions and the next pagination token
+func (s *HeliusSource) getTransactionsPage(ctx context.Context, address string, fromTime, toTime int64, afterSlot uint64, paginationToken *string) ([]RPCTransaction, *string, error) {
+	if err := s.limiter.Wait(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	url := fmt.Sprintf("%s?api-key=%s", s.url, s.apiKey)
+
+	filters := map[string]interface{}{
+		"blockTime": map[string]interface{}{
+			"gte": fromTime,
+			"lte": toTime,
+		},
+		"status": "succeeded",
+	}
+	if afterSlot > 0 {
+		filters["slot"] = map[string]interface{}{
+			"gt": afterSlot,
+		}
+	}
+
+	options := map[string]interface{}{
+		"transactionDetails": "full",
+		"limit":              100,
+		"sortOrder":          "asc",
+		"commitment":         "finalized",
+		"encoding":           "json",
+		// Versioned transactions are rejected without it
+		"maxSupportedTransactionVersion": 0,
+		"filters":                        filters,
+	}
+	if paginationToken != nil {
+		options["paginationToken"] = *paginationToken
+	}
+
+	reqBody := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      "1",
+		"method":  "getTransactionsForAddress",
+		"params":  []interface{}{address, options},
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get transactions: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, nil, fmt.Errorf("failed to get transactions: status %d, body: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	var rpcResp struct {
+		JSONRPC string `json:"jsonrpc"`
+		ID      string `json:"id"`
+		Result  *struct {
+			Data            []RPCTransaction `json:"data"`
+			PaginationToken *string          `json:"paginationToken"`
+		} `json:"result"`
+		Error *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+
+	if err := json.Unmarshal(bodyBytes, &rpcResp); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if rpcResp.Error != nil {
+		return nil, nil, fmt.Errorf("RPC error: %s (code: %d)", rpcResp.Error.Message, rpcResp.Error.Code)
+	}
+
+	if rpcResp.Result == nil {
+		return nil, nil, fmt.Errorf("empty result in RPC response")
+	}
+
+	var transactions []RPCTransaction
+	for _, tx := range rpcResp.Result.Data {
+		if tx.BlockTime == nil || *tx.BlockTime < fromTime || *tx.BlockTime > toTime || tx.signature() == "" {
+			continue
+		}
+		transactions = append(transactions, tx)
+	}
+
+	return transactions, rpcResp.Result.PaginationToken, nil
+}
@@ -8,6 +8,8 @@ import (
 	"time"
 
 	"github.com/dawitel/alchemy-webhook/cache"
+	"github.com/dawitel/alchemy-webhook/dispatch"
+	"github.com/dawitel/alchemy-webhook/watch"
 	"github.com/mr-tron/base58"
 	"github.com/rs/zerolog"
 )
@@ -17,11 +19,34 @@ type TransactionHandler func(ctx context.Context, tx ProcessedTransaction) error
 
 // Processor processes Solana webhook transactions
 type Processor struct {
-	logger     zerolog.Logger
-	cache      cache.Cache
-	tokenMints map[string]string // currency -> mint address
-	handler    TransactionHandler
-	chainID    string
+	logger        zerolog.Logger
+	cache         cache.Cache
+	tokenMints    map[string]string // currency -> mint address
+	subscribers   *dispatch.Dispatcher[ProcessedTransaction]
+	confirmations *ConfirmationTracker
+	blockTimes    *BlockTimes
+	watched       watch.AddressSet
+	directions    watch.Filter
+	chainID       string
+}
+
+// ProcessorOption configures optional Processor behaviour
+type ProcessorOption func(*Processor)
+
+// WithAddressSet tags each transfer with its direction relative to the
+// watched addresses, typically the addresses of the webhook
+func WithAddressSet(set watch.AddressSet) ProcessorOption {
+	return func(p *Processor) {
+		p.watched = set
+	}
+}
+
+// WithDirectionFilter delivers only transfers with one of the given
+// directions. It requires an address set.
+func WithDirectionFilter(directions ...watch.Direction) ProcessorOption {
+	return func(p *Processor) {
+		p.directions = watch.NewFilter(directions...)
+	}
 }
 
 // NewProcessor creates a new Solana processor
@@ -31,18 +56,35 @@ func NewProcessor(
 	tokenMints map[string]string, // currency -> mint address
 	handler TransactionHandler,
 	chainID string,
+	opts ...ProcessorOption,
 ) *Processor {
-	return &Processor{
-		logger:     logger,
-		cache:      cache,
-		tokenMints: tokenMints,
-		handler:    handler,
-		chainID:    chainID,
+	p := &Processor{
+		logger:      logger,
+		cache:       cache,
+		tokenMints:  tokenMints,
+		subscribers: dispatch.New[ProcessedTransaction](logger),
+		chainID:     chainID,
+	}
+
+	if handler != nil {
+		p.subscribers.Subscribe(Subscriber{Name: dispatch.DefaultSubscriberName, Handle: handler})
 	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
 }
 
 // ProcessTransaction processes a single Solana transaction from Alchemy webhook
 func (p *Processor) ProcessTransaction(ctx context.Context, alchemyTx AlchemySolanaTransaction, slot uint64) error {
+	return p.processTransaction(ctx, alchemyTx, slot, 0)
+}
+
+// processTransaction processes a transaction whose block time may already
+// be known, zero otherwise
+func (p *Processor) processTransaction(ctx context.Context, alchemyTx AlchemySolanaTransaction, slot uint64, blockTime int64) error {
 	if len(alchemyTx.Transaction) == 0 || len(alchemyTx.Meta) == 0 {
 		p.logger.Debug().
 			Str("signature", alchemyTx.Signature).
@@ -86,6 +128,7 @@ func (p *Processor) ProcessTransaction(ctx context.Context, alchemyTx AlchemySol
 
 	nativeTransfers := p.extractNativeTransfers(accountKeys, meta, alchemyTx.Signature)
 	tokenTransfers := p.extractTokenTransfers(accountKeys, msg, meta, alchemyTx.Signature)
+	nativeTransfers, tokenTransfers = p.classify(nativeTransfers, tokenTransfers, alchemyTx.Signature)
 
 	processedTx := ProcessedTransaction{
 		Signature:       alchemyTx.Signature,
@@ -93,14 +136,12 @@ func (p *Processor) ProcessTransaction(ctx context.Context, alchemyTx AlchemySol
 		NativeTransfers: nativeTransfers,
 		TokenTransfers:  tokenTransfers,
 		Fee:             meta.Fee,
-		Timestamp:       time.Now().Unix(),
+		Timestamp:       p.blockTime(ctx, alchemyTx.Signature, slot, blockTime),
 	}
 
 	if len(nativeTransfers) > 0 || len(tokenTransfers) > 0 {
-		if p.handler != nil {
-			if err := p.handler(ctx, processedTx); err != nil {
-				return fmt.Errorf("handler error: %w", err)
-			}
+		if err := p.subscribers.Dispatch(ctx, processedTx); err != nil {
+			return fmt.Errorf("handler error: %w", err)
 		}
 
 		if p.cache != nil {
@@ -109,11 +150,54 @@ func (p *Processor) ProcessTransaction(ctx context.Context, alchemyTx AlchemySol
 				p.logger.Warn().Err(err).Str("signature", alchemyTx.Signature).Msg("Failed to mark transaction as processed")
 			}
 		}
+
+		if p.confirmations != nil && slot > 0 {
+			if err := p.confirmations.Track(ctx, processedTx); err != nil {
+				p.logger.Warn().Err(err).Str("signature", alchemyTx.Signature).Msg("Failed to track confirmations")
+			}
+		}
 	}
 
 	return nil
 }
 
+// classify sets the direction of each transfer and drops the ones excluded
+// by the direction filter. Token transfers are matched on the owner accounts
+// first and on the token accounts otherwise.
+func (p *Processor) classify(native []NativeTransfer, tokens []TokenTransfer, signature string) ([]NativeTransfer, []TokenTransfer) {
+	if p.watched == nil {
+		return native, tokens
+	}
+
+	keptNative := native[:0]
+	for _, transfer := range native {
+		transfer.Direction, transfer.WatchedAddress = watch.Classify(p.watched, transfer.FromUserAccount, transfer.ToUserAccount)
+		if p.directions.Allows(transfer.Direction) {
+			keptNative = append(keptNative, transfer)
+		}
+	}
+
+	keptTokens := tokens[:0]
+	for _, transfer := range tokens {
+		transfer.Direction, transfer.WatchedAddress = watch.Classify(p.watched, transfer.FromUserAccount, transfer.ToUserAccount)
+		if transfer.Direction == watch.DirectionUnrelated {
+			transfer.Direction, transfer.WatchedAddress = watch.Classify(p.watched, transfer.FromTokenAccount, transfer.ToTokenAccount)
+		}
+		if p.directions.Allows(transfer.Direction) {
+			keptTokens = append(keptTokens, transfer)
+		}
+	}
+
+	if dropped := len(native) - len(keptNative) + len(tokens) - len(keptTokens); dropped > 0 {
+		p.logger.Debug().
+			Str("signature", signature).
+			Int("dropped_transfers", dropped).
+			Msg("Transfer direction filtered, skipping")
+	}
+
+	return keptNative, keptTokens
+}
+
 // extractNativeTransfers extracts native SOL transfers from balance changes
 func (p *Processor) extractNativeTransfers(accountKeys []string, meta AlchemySolanaTxMeta, signature string) []NativeTransfer {
 	var nativeTransfers []NativeTransfer
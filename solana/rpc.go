@@ -0,0 +1,208 @@
+package solana
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Commitment levels
+const (
+	CommitmentProcessed = "processed"
+	CommitmentConfirmed = "confirmed"
+	CommitmentFinalized = "finalized"
+)
+
+// SignatureStatus is the status of a transaction signature
+type SignatureStatus struct {
+	Slot               uint64          `json:"slot"`
+	Confirmations      *uint64         `json:"confirmations"` // nil once finalized
+	Err                json.RawMessage `json:"err"`
+	ConfirmationStatus string          `json:"confirmationStatus"`
+}
+
+// RPCClient is a minimal Solana JSON-RPC client
+type RPCClient struct {
+	url        string
+	httpClient *http.Client
+}
+
+// NewRPCClient creates a new Solana JSON-RPC client
+func NewRPCClient(url string, httpClient *http.Client) *RPCClient {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	return &RPCClient{
+		url:        url,
+		httpClient: httpClient,
+	}
+}
+
+// GetSlot returns the current slot at the given commitment
+func (c *RPCClient) GetSlot(ctx context.Context, commitment string) (uint64, error) {
+	var slot uint64
+	params := []interface{}{map[string]interface{}{"commitment": commitment}}
+	if err := c.call(ctx, "getSlot", params, &slot); err != nil {
+		return 0, err
+	}
+	return slot, nil
+}
+
+// GetBlockTime returns the production time of a slot in Unix seconds
+func (c *RPCClient) GetBlockTime(ctx context.Context, slot uint64) (int64, error) {
+	var blockTime *int64
+	if err := c.call(ctx, "getBlockTime", []interface{}{slot}, &blockTime); err != nil {
+		return 0, err
+	}
+	if blockTime == nil {
+		return 0, fmt.Errorf("block time of slot %d not available", slot)
+	}
+	return *blockTime, nil
+}
+
+// GetSignatureStatuses returns the status of each signature. Unknown
+// signatures have a nil status.
+func (c *RPCClient) GetSignatureStatuses(ctx context.Context, signatures []string) ([]*SignatureStatus, error) {
+	var result struct {
+		Value []*SignatureStatus `json:"value"`
+	}
+	params := []interface{}{
+		signatures,
+		map[string]interface{}{"searchTransactionHistory": true},
+	}
+	if err := c.call(ctx, "getSignatureStatuses", params, &result); err != nil {
+		return nil, err
+	}
+	if len(result.Value) != len(signatures) {
+		return nil, fmt.Errorf("expected %d statuses, got %d", len(signatures), len(result.Value))
+	}
+	return result.Value, nil
+}
+
+// SignatureInfo is an entry of getSignaturesForAddress
+type SignatureInfo struct {
+	Signature          string          `json:"signature"`
+	Slot               uint64          `json:"slot"`
+	Err                json.RawMessage `json:"err"`
+	BlockTime          *int64          `json:"blockTime"`
+	ConfirmationStatus string          `json:"confirmationStatus"`
+}
+
+// SignaturesOptions selects a page of getSignaturesForAddress. Before and
+// Until are signatures; zero values are left out of the request.
+type SignaturesOptions struct {
+	Before     string // Start searching backwards from this signature
+	Until      string // Stop searching at this signature
+	Limit      int    // Up to 1000, the node default when zero
+	Commitment string
+}
+
+// GetSignaturesForAddress returns the signatures of transactions involving
+// address, newest first
+func (c *RPCClient) GetSignaturesForAddress(ctx context.Context, address string, opts SignaturesOptions) ([]SignatureInfo, error) {
+	config := map[string]interface{}{}
+	if opts.Before != "" {
+		config["before"] = opts.Before
+	}
+	if opts.Until != "" {
+		config["until"] = opts.Until
+	}
+	if opts.Limit > 0 {
+		config["limit"] = opts.Limit
+	}
+	if opts.Commitment != "" {
+		config["commitment"] = opts.Commitment
+	}
+
+	var signatures []SignatureInfo
+	if err := c.call(ctx, "getSignaturesForAddress", []interface{}{address, config}, &signatures); err != nil {
+		return nil, err
+	}
+	return signatures, nil
+}
+
+// GetTransaction returns a transaction in the "json" encoding, versioned
+// transactions included, or nil when the node does not know it
+func (c *RPCClient) GetTransaction(ctx context.Context, signature string, commitment string) (*RPCTransaction, error) {
+	config := map[string]interface{}{
+		"encoding":                       "json",
+		"maxSupportedTransactionVersion": 0,
+	}
+	if commitment != "" {
+		config["commitment"] = commitment
+	}
+
+	var tx *RPCTransaction
+	if err := c.call(ctx, "getTransaction", []interface{}{signature, config}, &tx); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// call performs a JSON-RPC request and decodes the result into out
+func (c *RPCClient) call(ctx context.Context, method string, params []interface{}, out interface{}) error {
+	reqBody := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  method,
+		"params":  params,
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call %s: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to call %s: status %d, body: %s", method, resp.StatusCode, string(bodyBytes))
+	}
+
+	var rpcResp struct {
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+
+	if err := json.Unmarshal(bodyBytes, &rpcResp); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if rpcResp.Error != nil {
+		return fmt.Errorf("RPC error: %s (code: %d)", rpcResp.Error.Message, rpcResp.Error.Code)
+	}
+
+	if len(rpcResp.Result) == 0 {
+		return fmt.Errorf("empty result in RPC response")
+	}
+
+	if err := json.Unmarshal(rpcResp.Result, out); err != nil {
+		return fmt.Errorf("failed to decode %s result: %w", method, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package solana
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dawitel/alchemy-webhook/ratelimit"
+)
+
+// signaturesPageSize is the largest page getSignaturesForAddress returns
+const signaturesPageSize = 1000
+
+// Position is the newest transaction a previous backfill of an address
+// processed. The zero value starts from the beginning of the time range.
+type Position struct {
+	Slot      uint64
+	Signature string
+}
+
+// Source fetches the history of an address for backfill
+type Source interface {
+	// Transactions returns the successful transactions of address with a
+	// block time within [fromTime, toTime] (Unix seconds) that are newer
+	// than after, oldest first. Backfill passes a zero fromTime when it
+	// resumes from after, so only after bounds the start.
+	Transactions(ctx context.Context, address string, fromTime, toTime int64, after Position) ([]RPCTransaction, error)
+}
+
+// RPCSource is a Source built on the standard getSignaturesForAddress and
+// getTransaction methods, so it works against any Solana RPC node
+type RPCSource struct {
+	client  *RPCClient
+	limiter *ratelimit.Limiter
+}
+
+// NewRPCSource creates a source on client. Every RPC call waits for limiter;
+// a nil limiter does not limit.
+func NewRPCSource(client *RPCClient, limiter *ratelimit.Limiter) *RPCSource {
+	return &RPCSource{
+		client:  client,
+		limiter: limiter,
+	}
+}
+
+// Transactions pages backwards through the signatures of address with
+// "before" until it passes fromTime or after, then fetches each transaction.
+// The signature of after is passed as "until", so the node stops there.
+func (s *RPCSource) Transactions(ctx context.Context, address string, fromTime, toTime int64, after Position) ([]RPCTransaction, error) {
+	var signatures []string
+	before := ""
+	for {
+		if err := s.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+
+		page, err := s.client.GetSignaturesForAddress(ctx, address, SignaturesOptions{
+			Before:     before,
+			Until:      after.Signature,
+			Limit:      signaturesPageSize,
+			Commitment: CommitmentFinalized,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get signatures: %w", err)
+		}
+
+		done := len(page) < signaturesPageSize
+		for _, info := range page {
+			if info.Slot <= after.Slot || (info.BlockTime != nil && *info.BlockTime < fromTime) {
+				done = true
+				break
+			}
+			if !isJSONNull(info.Err) || info.BlockTime == nil || *info.BlockTime > toTime {
+				continue
+			}
+			signatures = append(signatures, info.Signature)
+		}
+		if done {
+			break
+		}
+		before = page[len(page)-1].Signature
+	}
+
+	transactions := make([]RPCTransaction, 0, len(signatures))
+	for i := len(signatures) - 1; i >= 0; i-- {
+		if err := s.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+
+		tx, err := s.client.GetTransaction(ctx, signatures[i], CommitmentFinalized)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get transaction %s: %w", signatures[i], err)
+		}
+		if tx == nil {
+			continue
+		}
+		transactions = append(transactions, *tx)
+	}
+
+	return transactions, nil
+}
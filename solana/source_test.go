@@ -0,0 +1,341 @@
+package solana
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dawitel/alchemy-webhook/backfill"
+	"github.com/dawitel/alchemy-webhook/cache"
+	"github.com/dawitel/alchemy-webhook/checkpoint"
+	"github.com/rs/zerolog"
+)
+
+// ledgerEntry is a transaction of the address served by the fake RPC node
+type ledgerEntry struct {
+	signature string
+	slot      uint64
+	blockTime int64
+	failed    bool
+}
+
+// fakeNode is a Solana JSON-RPC server answering getSignaturesForAddress and
+// getTransaction from a ledger ordered newest first
+type fakeNode struct {
+	ledger []ledgerEntry
+
+	mu     sync.Mutex
+	calls  map[string]int
+	untils []string
+}
+
+// newFakeNode starts a fake node and returns an RPC client connected to it
+func newFakeNode(t *testing.T, ledger []ledgerEntry) (*fakeNode, *RPCClient) {
+	t.Helper()
+
+	node := &fakeNode{ledger: ledger, calls: make(map[string]int)}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage   `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+			return
+		}
+
+		node.mu.Lock()
+		node.calls[req.Method]++
+		node.mu.Unlock()
+
+		var result interface{}
+		switch req.Method {
+		case "getSignaturesForAddress":
+			var opts struct {
+				Before string `json:"before"`
+				Until  string `json:"until"`
+				Limit  int    `json:"limit"`
+			}
+			json.Unmarshal(req.Params[1], &opts)
+			node.mu.Lock()
+			node.untils = append(node.untils, opts.Until)
+			node.mu.Unlock()
+			result = node.signatures(opts.Before, opts.Until, opts.Limit)
+		case "getTransaction":
+			var signature string
+			json.Unmarshal(req.Params[0], &signature)
+			result = node.transaction(signature)
+		default:
+			t.Errorf("unexpected method %s", req.Method)
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": result})
+	}))
+	t.Cleanup(server.Close)
+
+	return node, NewRPCClient(server.URL, nil)
+}
+
+// signatures returns up to limit entries older than before and newer than
+// until
+func (n *fakeNode) signatures(before, until string, limit int) []map[string]interface{} {
+	start, end := 0, len(n.ledger)
+	for i, entry := range n.ledger {
+		switch entry.signature {
+		case before:
+			start = i + 1
+		case until:
+			end = i
+		}
+	}
+
+	page := []map[string]interface{}{}
+	for _, entry := range n.ledger[start:max(start, min(start+limit, end))] {
+		var txErr interface{}
+		if entry.failed {
+			txErr = map[string]interface{}{"InstructionError": []interface{}{0, "InvalidAccountData"}}
+		}
+		page = append(page, map[string]interface{}{
+			"signature":          entry.signature,
+			"slot":               entry.slot,
+			"err":                txErr,
+			"blockTime":          entry.blockTime,
+			"confirmationStatus": CommitmentFinalized,
+		})
+	}
+	return page
+}
+
+// transaction returns the transaction of signature, nil when unknown
+func (n *fakeNode) transaction(signature string) json.RawMessage {
+	for _, entry := range n.ledger {
+		if entry.signature == signature {
+			return transferCheckedTx(entry.signature, entry.slot, entry.blockTime, 1000000, nil)
+		}
+	}
+	return nil
+}
+
+// ledger returns count entries newest first, one per slot ending at
+// lastSlot, with block time base + slot
+func ledger(count int, lastSlot uint64, base int64) []ledgerEntry {
+	entries := make([]ledgerEntry, count)
+	for i := range entries {
+		slot := lastSlot - uint64(i)
+		entries[i] = ledgerEntry{signature: testSignature(int(slot)), slot: slot, blockTime: base + int64(slot)}
+	}
+	return entries
+}
+
+func TestRPCSourcePagesBackToRangeStart(t *testing.T) {
+	const base = 1700000000
+	entries := ledger(1200, 5000, base)
+	entries[100].failed = true // slot 4900
+
+	node, client := newFakeNode(t, entries)
+	source := NewRPCSource(client, nil)
+
+	transactions, err := source.Transactions(context.Background(), testOwner, base+3900, base+4990, Position{Slot: 3950})
+	if err != nil {
+		t.Fatalf("Transactions returned error: %v", err)
+	}
+
+	// Slots 3951 to 4990 without the failed one at 4900
+	if len(transactions) != 1039 {
+		t.Fatalf("got %d transactions, want 1039", len(transactions))
+	}
+	if first, last := transactions[0].Slot, transactions[len(transactions)-1].Slot; first != 3951 || last != 4990 {
+		t.Errorf("transactions span slots %d to %d, want 3951 to 4990 oldest first", first, last)
+	}
+	for _, tx := range transactions {
+		if tx.Slot == 4900 {
+			t.Error("failed transaction returned")
+		}
+	}
+	if calls := node.calls["getSignaturesForAddress"]; calls != 2 {
+		t.Errorf("getSignaturesForAddress called %d times, want 2", calls)
+	}
+}
+
+func TestBackfillWithRPCSource(t *testing.T) {
+	// Three transactions in the last minute
+	const lastSlot = 3000
+	_, client := newFakeNode(t, ledger(3, lastSlot, time.Now().Unix()-60-lastSlot))
+
+	var mu sync.Mutex
+	var got []ProcessedTransaction
+	processor := NewProcessor(zerolog.Nop(), nil, map[string]string{"USDC": testMint}, func(ctx context.Context, tx ProcessedTransaction) error {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, tx)
+		return nil
+	}, "sol-mainnet")
+
+	b := NewBackfill(NewRPCSource(client, nil), processor, zerolog.Nop(), nil, time.Hour, 10)
+	job, err := b.Backfill(context.Background(), []string{testOwner})
+	if err != nil {
+		t.Fatalf("Backfill returned error: %v", err)
+	}
+	if err := job.Wait(context.Background()); err != nil {
+		t.Fatalf("backfill job failed: %v", err)
+	}
+
+	if job.Status() != backfill.StatusSucceeded {
+		t.Errorf("job status = %s, want %s", job.Status(), backfill.StatusSucceeded)
+	}
+	want := backfill.Progress{AddressesTotal: 1, AddressesDone: 1, Processed: 3}
+	if progress := job.Progress(); progress != want {
+		t.Errorf("job progress = %+v, want %+v", progress, want)
+	}
+	if len(got) != 3 {
+		t.Fatalf("handler called %d times, want 3", len(got))
+	}
+	for _, tx := range got {
+		if len(tx.TokenTransfers) != 1 || tx.TokenTransfers[0].TokenAmount != 1 {
+			t.Errorf("transaction %s token transfers = %+v, want one of 1 USDC", tx.Signature, tx.TokenTransfers)
+		}
+	}
+}
+
+func TestBackfillResumesUntilCheckpointSignature(t *testing.T) {
+	// Three transactions in the last minute, two more arriving before the
+	// second run
+	const lastSlot = 3000
+	base := time.Now().Unix() - 60 - lastSlot
+	entries := ledger(5, lastSlot, base)
+	node, client := newFakeNode(t, entries[2:])
+
+	var mu sync.Mutex
+	var got []string
+	processor := NewProcessor(zerolog.Nop(), nil, map[string]string{"USDC": testMint}, func(ctx context.Context, tx ProcessedTransaction) error {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, tx.Signature)
+		return nil
+	}, "sol-mainnet")
+
+	store := checkpoint.NewMemoryStore()
+	b := NewBackfill(NewRPCSource(client, nil), processor, zerolog.Nop(), nil, time.Hour, 10, WithCheckpoints(store))
+	run := func() {
+		t.Helper()
+		job, err := b.Backfill(context.Background(), []string{testOwner})
+		if err != nil {
+			t.Fatalf("Backfill returned error: %v", err)
+		}
+		if err := job.Wait(context.Background()); err != nil {
+			t.Fatalf("backfill job failed: %v", err)
+		}
+	}
+
+	run()
+	cp, ok, err := store.Get(context.Background(), b.checkpointKey(testOwner))
+	if err != nil || !ok {
+		t.Fatalf("checkpoint not saved: ok=%v err=%v", ok, err)
+	}
+	if cp.Height != entries[2].slot || cp.Cursor != entries[2].signature {
+		t.Errorf("checkpoint = slot %d cursor %s, want slot %d cursor %s", cp.Height, cp.Cursor, entries[2].slot, entries[2].signature)
+	}
+
+	node.mu.Lock()
+	node.ledger = entries
+	node.untils = nil
+	node.mu.Unlock()
+	got = nil
+
+	run()
+	if len(node.untils) == 0 || node.untils[0] != entries[2].signature {
+		t.Errorf("resumed with until %v, want %s", node.untils, entries[2].signature)
+	}
+	want := []string{entries[1].signature, entries[0].signature}
+	if !slices.Equal(got, want) {
+		t.Errorf("resumed backfill processed %v, want %v", got, want)
+	}
+}
+
+func TestBackfillCheckpointOutlivesTimeRange(t *testing.T) {
+	// Every transaction is two hours old, past the one hour time range
+	const lastSlot = 3000
+	entries := ledger(5, lastSlot, time.Now().Unix()-2*3600-lastSlot)
+	_, client := newFakeNode(t, entries)
+
+	var got []string
+	processor := NewProcessor(zerolog.Nop(), nil, map[string]string{"USDC": testMint}, func(ctx context.Context, tx ProcessedTransaction) error {
+		got = append(got, tx.Signature)
+		return nil
+	}, "sol-mainnet")
+
+	store := checkpoint.NewMemoryStore()
+	b := NewBackfill(NewRPCSource(client, nil), processor, zerolog.Nop(), nil, time.Hour, 10, WithCheckpoints(store))
+	cp := checkpoint.Checkpoint{Key: b.checkpointKey(testOwner), Height: entries[3].slot, Cursor: entries[3].signature}
+	if err := store.Put(context.Background(), cp); err != nil {
+		t.Fatalf("failed to store checkpoint: %v", err)
+	}
+
+	job, err := b.Backfill(context.Background(), []string{testOwner})
+	if err != nil {
+		t.Fatalf("Backfill returned error: %v", err)
+	}
+	if err := job.Wait(context.Background()); err != nil {
+		t.Fatalf("backfill job failed: %v", err)
+	}
+
+	want := []string{entries[2].signature, entries[1].signature, entries[0].signature}
+	if !slices.Equal(got, want) {
+		t.Errorf("processed %v, want every transaction after the checkpoint %v", got, want)
+	}
+}
+
+// staticSource is a Source returning fixed transactions
+type staticSource []RPCTransaction
+
+func (s staticSource) Transactions(ctx context.Context, address string, fromTime, toTime int64, after Position) ([]RPCTransaction, error) {
+	return s, nil
+}
+
+func TestBackfillSkipsProcessedTransactions(t *testing.T) {
+	var transactions staticSource
+	for _, entry := range ledger(5, testSlot, testBlockTime) {
+		var tx RPCTransaction
+		if err := json.Unmarshal(transferCheckedTx(entry.signature, entry.slot, entry.blockTime, 1000000, nil), &tx); err != nil {
+			t.Fatalf("failed to parse transaction: %v", err)
+		}
+		transactions = append(transactions, tx)
+	}
+
+	memoryCache := cache.NewMemoryCache(100, time.Minute, false)
+	defer memoryCache.Close()
+	for _, i := range []int{1, 3} {
+		memoryCache.MarkProcessed(context.Background(), transactions[i].signature(), time.Hour)
+	}
+
+	var got []string
+	processor := NewProcessor(zerolog.Nop(), memoryCache, map[string]string{"USDC": testMint}, func(ctx context.Context, tx ProcessedTransaction) error {
+		got = append(got, tx.Signature)
+		return nil
+	}, "sol-mainnet")
+
+	// A page size of 2 puts processed and new transactions on the same pages
+	b := NewBackfill(transactions, processor, zerolog.Nop(), memoryCache, time.Hour, 2)
+	job, err := b.Backfill(context.Background(), []string{testOwner})
+	if err != nil {
+		t.Fatalf("Backfill returned error: %v", err)
+	}
+	if err := job.Wait(context.Background()); err != nil {
+		t.Fatalf("backfill job failed: %v", err)
+	}
+
+	want := backfill.Progress{AddressesTotal: 1, AddressesDone: 1, Processed: 3, Skipped: 2}
+	if progress := job.Progress(); progress != want {
+		t.Errorf("job progress = %+v, want %+v", progress, want)
+	}
+	wantSignatures := []string{transactions[0].signature(), transactions[2].signature(), transactions[4].signature()}
+	if !slices.Equal(got, wantSignatures) {
+		t.Errorf("processed %v, want %v", got, wantSignatures)
+	}
+}
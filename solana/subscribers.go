@@ -0,0 +1,36 @@
+package solana
+
+import (
+	"github.com/dawitel/alchemy-webhook/dispatch"
+)
+
+// Subscriber receives processed transactions. See dispatch.Subscriber.
+type Subscriber = dispatch.Subscriber[ProcessedTransaction]
+
+// WithSubscriber registers an additional subscriber. Registration errors,
+// such as a duplicate name, are logged; use Processor.Subscribe to handle them.
+func WithSubscriber(sub Subscriber) ProcessorOption {
+	return func(p *Processor) {
+		if err := p.subscribers.Subscribe(sub); err != nil {
+			p.logger.Error().Err(err).Str("subscriber", sub.Name).Msg("Failed to register subscriber")
+		}
+	}
+}
+
+// Subscribe registers a subscriber. Transactions are marked as processed only
+// after every required subscriber has succeeded; subscribers that did succeed
+// receive the transaction again on redelivery, so they should be idempotent on
+// Signature.
+func (p *Processor) Subscribe(sub Subscriber) error {
+	return p.subscribers.Subscribe(sub)
+}
+
+// Unsubscribe removes a subscriber and reports whether it was registered
+func (p *Processor) Unsubscribe(name string) bool {
+	return p.subscribers.Unsubscribe(name)
+}
+
+// Subscribers returns the names of the registered subscribers
+func (p *Processor) Subscribers() []string {
+	return p.subscribers.Subscribers()
+}
@@ -0,0 +1,155 @@
+package solana
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// RPCTransaction is a transaction in the "json" encoding of getTransaction,
+// which getTransactionsForAddress also returns with full transaction details
+type RPCTransaction struct {
+	Slot        uint64          `json:"slot"`
+	BlockTime   *int64          `json:"blockTime"`
+	Version     json.RawMessage `json:"version"` // "legacy" or 0, absent for legacy transactions
+	Transaction struct {
+		Signatures []string   `json:"signatures"`
+		Message    RPCMessage `json:"message"`
+	} `json:"transaction"`
+	Meta *RPCTransactionMeta `json:"meta"`
+}
+
+// RPCMessage is the message of an RPCTransaction
+type RPCMessage struct {
+	Header struct {
+		NumRequiredSignatures       int `json:"numRequiredSignatures"`
+		NumReadonlySignedAccounts   int `json:"numReadonlySignedAccounts"`
+		NumReadonlyUnsignedAccounts int `json:"numReadonlyUnsignedAccounts"`
+	} `json:"header"`
+	AccountKeys     []string         `json:"accountKeys"`
+	RecentBlockhash string           `json:"recentBlockhash"`
+	Instructions    []RPCInstruction `json:"instructions"`
+}
+
+// RPCInstruction is a compiled instruction with base58 data
+type RPCInstruction struct {
+	ProgramIDIndex int    `json:"programIdIndex"`
+	Accounts       []int  `json:"accounts"`
+	Data           string `json:"data"`
+}
+
+// RPCTransactionMeta is the status metadata of an RPCTransaction. Nil slices
+// mean the node did not record them.
+type RPCTransactionMeta struct {
+	Err               json.RawMessage `json:"err"`
+	Fee               int64           `json:"fee"`
+	PreBalances       []int64         `json:"preBalances"`
+	PostBalances      []int64         `json:"postBalances"`
+	InnerInstructions []struct {
+		Index        int              `json:"index"`
+		Instructions []RPCInstruction `json:"instructions"`
+	} `json:"innerInstructions"`
+	LogMessages     []string `json:"logMessages"`
+	LoadedAddresses *struct {
+		Writable []string `json:"writable"`
+		Readonly []string `json:"readonly"`
+	} `json:"loadedAddresses"`
+	ReturnData           json.RawMessage `json:"returnData"`
+	ComputeUnitsConsumed int64           `json:"computeUnitsConsumed"`
+}
+
+// signature returns the transaction signature, the first of its signatures
+func (tx RPCTransaction) signature() string {
+	if len(tx.Transaction.Signatures) == 0 {
+		return ""
+	}
+	return tx.Transaction.Signatures[0]
+}
+
+// blockTime returns the block time of the transaction, zero when unknown
+func (tx RPCTransaction) blockTime() int64 {
+	if tx.BlockTime == nil {
+		return 0
+	}
+	return *tx.BlockTime
+}
+
+// succeeded reports whether the transaction executed without error
+func (tx RPCTransaction) succeeded() bool {
+	return tx.Meta != nil && isJSONNull(tx.Meta.Err)
+}
+
+// convertToAlchemyTx maps a fetched transaction into the webhook model so it
+// goes through the same extraction as webhook deliveries. Addresses loaded
+// from lookup tables are appended to the account keys, writable first, the
+// order instruction indexes refer to. It returns nil for transactions
+// without metadata or that failed, whose balance changes are only fees.
+func convertToAlchemyTx(tx RPCTransaction) *AlchemySolanaTransaction {
+	if !tx.succeeded() {
+		return nil
+	}
+	meta := tx.Meta
+
+	accountKeys := append([]string(nil), tx.Transaction.Message.AccountKeys...)
+	if meta.LoadedAddresses != nil {
+		accountKeys = append(accountKeys, meta.LoadedAddresses.Writable...)
+		accountKeys = append(accountKeys, meta.LoadedAddresses.Readonly...)
+	}
+
+	header := tx.Transaction.Message.Header
+	msg := AlchemySolanaTxMessage{
+		Header: []AlchemySolanaTxHeader{{
+			NumRequiredSignatures:       header.NumRequiredSignatures,
+			NumReadonlySignedAccounts:   header.NumReadonlySignedAccounts,
+			NumReadonlyUnsignedAccounts: header.NumReadonlyUnsignedAccounts,
+		}},
+		Instructions:    convertInstructions(tx.Transaction.Message.Instructions),
+		Versioned:       len(tx.Version) > 0 && !bytes.Equal(tx.Version, []byte(`"legacy"`)),
+		AccountKeys:     accountKeys,
+		RecentBlockhash: tx.Transaction.Message.RecentBlockhash,
+	}
+
+	alchemyMeta := AlchemySolanaTxMeta{
+		Fee:                   meta.Fee,
+		PreBalances:           meta.PreBalances,
+		PostBalances:          meta.PostBalances,
+		InnerInstructionsNone: meta.InnerInstructions == nil,
+		LogMessages:           meta.LogMessages,
+		LogMessagesNone:       meta.LogMessages == nil,
+		ReturnDataNone:        isJSONNull(meta.ReturnData),
+		ComputeUnitsConsumed:  meta.ComputeUnitsConsumed,
+	}
+	for _, inner := range meta.InnerInstructions {
+		alchemyMeta.InnerInstructions = append(alchemyMeta.InnerInstructions, AlchemySolanaInnerInstruction{
+			Index:        inner.Index,
+			Instructions: convertInstructions(inner.Instructions),
+		})
+	}
+
+	alchemyTx := &AlchemySolanaTransaction{
+		Signature: tx.signature(),
+		Transaction: []AlchemySolanaTxDetail{{
+			Signatures: tx.Transaction.Signatures,
+			Message:    []AlchemySolanaTxMessage{msg},
+		}},
+		Meta: []AlchemySolanaTxMeta{alchemyMeta},
+	}
+	return alchemyTx
+}
+
+// convertInstructions maps compiled instructions into the webhook model
+func convertInstructions(instructions []RPCInstruction) []AlchemySolanaInstruction {
+	converted := make([]AlchemySolanaInstruction, len(instructions))
+	for i, instruction := range instructions {
+		converted[i] = AlchemySolanaInstruction{
+			Data:           instruction.Data,
+			ProgramIDIndex: instruction.ProgramIDIndex,
+			Accounts:       instruction.Accounts,
+		}
+	}
+	return converted
+}
+
+// isJSONNull reports whether a raw JSON value is absent or null
+func isJSONNull(raw json.RawMessage) bool {
+	return len(raw) == 0 || bytes.Equal(raw, []byte("null"))
+}
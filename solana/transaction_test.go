@@ -0,0 +1,132 @@
+package solana
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/mr-tron/base58"
+	"github.com/rs/zerolog"
+)
+
+const (
+	testMint         = "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v"
+	testOwner        = "9WzDXwBbmkg8ZTbNMqUxvQRAyrZzDsGYdLVL9zYtAWWM"
+	testSourceToken  = "3emsAVdmGKERbHjmGfQ6oZ1e35dkf5iYcS6U4CPKFVaa"
+	testDestToken    = "7UX2i7SucgLMQcfZ75s3VXmZZY4YRUyJN9X1RgfMoDUi"
+	splTokenProgram  = "TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA"
+	testSlot         = 250000000
+	testBlockTime    = 1700000000
+	testSignatureFmt = "5VERv8NMvzbJMEkV8xnrLkEaWRtSz9CosKDYjCJjBRnbJLgp8uirBgmQpjKhoR4tjF3ZpRzrFmBV6UjKdiSZkQU%d"
+)
+
+// testSignature returns a distinct transaction signature for n
+func testSignature(n int) string {
+	return fmt.Sprintf(testSignatureFmt, n)
+}
+
+// transferCheckedTx returns a versioned getTransaction result moving amount
+// base units of the test mint, which is loaded from a lookup table
+func transferCheckedTx(signature string, slot uint64, blockTime int64, amount uint64, txErr interface{}) []byte {
+	data := make([]byte, 10)
+	data[0] = 12
+	binary.LittleEndian.PutUint64(data[1:9], amount)
+	data[9] = 6
+
+	tx := map[string]interface{}{
+		"slot":      slot,
+		"blockTime": blockTime,
+		"version":   0,
+		"transaction": map[string]interface{}{
+			"signatures": []string{signature},
+			"message": map[string]interface{}{
+				"header": map[string]interface{}{
+					"numRequiredSignatures":       1,
+					"numReadonlySignedAccounts":   0,
+					"numReadonlyUnsignedAccounts": 1,
+				},
+				"accountKeys":     []string{testOwner, testSourceToken, testDestToken, splTokenProgram},
+				"recentBlockhash": "EkSnNWid2cvwEVnVx9aBqawnmiCNiDgp3gUdkDPTKN1N",
+				"instructions": []interface{}{
+					map[string]interface{}{
+						"programIdIndex": 3,
+						"accounts":       []int{1, 4, 2, 0},
+						"data":           base58.Encode(data),
+					},
+				},
+				"addressTableLookups": []interface{}{},
+			},
+		},
+		"meta": map[string]interface{}{
+			"err":               txErr,
+			"fee":               5000,
+			"preBalances":       []int64{10000000, 2039280, 2039280, 1, 1461600},
+			"postBalances":      []int64{9995000, 2039280, 2039280, 1, 1461600},
+			"innerInstructions": []interface{}{},
+			"logMessages":       []string{"Program " + splTokenProgram + " invoke [1]"},
+			"loadedAddresses": map[string]interface{}{
+				"writable": []string{},
+				"readonly": []string{testMint},
+			},
+			"computeUnitsConsumed": 6200,
+		},
+	}
+
+	raw, _ := json.Marshal(tx)
+	return raw
+}
+
+func TestConvertToAlchemyTxDetectsTokenTransfer(t *testing.T) {
+	var tx RPCTransaction
+	if err := json.Unmarshal(transferCheckedTx(testSignature(1), testSlot, testBlockTime, 1500000, nil), &tx); err != nil {
+		t.Fatalf("failed to decode transaction: %v", err)
+	}
+
+	alchemyTx := convertToAlchemyTx(tx)
+	if alchemyTx == nil {
+		t.Fatal("convertToAlchemyTx returned nil for a successful transaction")
+	}
+	if keys := alchemyTx.Transaction[0].Message[0].AccountKeys; len(keys) != 5 || keys[4] != testMint {
+		t.Errorf("account keys = %v, want the static keys followed by the loaded mint", keys)
+	}
+	if !alchemyTx.Transaction[0].Message[0].Versioned {
+		t.Error("version 0 transaction not marked as versioned")
+	}
+
+	var got []ProcessedTransaction
+	processor := NewProcessor(zerolog.Nop(), nil, map[string]string{"USDC": testMint}, func(ctx context.Context, tx ProcessedTransaction) error {
+		got = append(got, tx)
+		return nil
+	}, "sol-mainnet")
+	if err := processor.processTransaction(context.Background(), *alchemyTx, tx.Slot, tx.blockTime()); err != nil {
+		t.Fatalf("processTransaction returned error: %v", err)
+	}
+
+	if len(got) != 1 {
+		t.Fatalf("handler called %d times, want 1", len(got))
+	}
+	if got[0].Slot != testSlot || got[0].Timestamp != testBlockTime || got[0].Fee != 5000 {
+		t.Errorf("transaction = %+v, want slot %d, block time %d and fee 5000", got[0], testSlot, testBlockTime)
+	}
+	if len(got[0].TokenTransfers) != 1 {
+		t.Fatalf("token transfers = %+v, want one", got[0].TokenTransfers)
+	}
+	transfer := got[0].TokenTransfers[0]
+	if transfer.FromTokenAccount != testSourceToken || transfer.ToTokenAccount != testDestToken || transfer.TokenAmount != 1.5 || transfer.Currency != "USDC" {
+		t.Errorf("token transfer = %+v, want 1.5 USDC from %s to %s", transfer, testSourceToken, testDestToken)
+	}
+}
+
+func TestConvertToAlchemyTxSkipsFailedTransactions(t *testing.T) {
+	var tx RPCTransaction
+	failure := map[string]interface{}{"InstructionError": []interface{}{0, "InvalidAccountData"}}
+	if err := json.Unmarshal(transferCheckedTx(testSignature(2), testSlot, testBlockTime, 1500000, failure), &tx); err != nil {
+		t.Fatalf("failed to decode transaction: %v", err)
+	}
+
+	if alchemyTx := convertToAlchemyTx(tx); alchemyTx != nil {
+		t.Errorf("convertToAlchemyTx = %+v, want nil for a failed transaction", alchemyTx)
+	}
+}
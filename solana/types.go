@@ -1,5 +1,7 @@
 package solana
 
+import "github.com/dawitel/alchemy-webhook/watch"
+
 // AlchemySolanaWebhookPayload represents the webhook payload from Alchemy for Solana
 type AlchemySolanaWebhookPayload struct {
 	WebhookID string `json:"webhookId"`
@@ -129,7 +131,9 @@ type AlchemyWebhookAddressesResponse struct {
 type NativeTransfer struct {
 	FromUserAccount string
 	ToUserAccount   string
-	Amount          int64 // lamports
+	Amount          int64           // lamports
+	Direction       watch.Direction // Empty when no watched address set is configured
+	WatchedAddress  string          // Watched address the direction was derived from
 }
 
 // TokenTransfer represents an SPL token transfer
@@ -141,6 +145,8 @@ type TokenTransfer struct {
 	TokenAmount      float64
 	Mint             string
 	Currency         string
+	Direction        watch.Direction // Empty when no watched address set is configured
+	WatchedAddress   string          // Watched address the direction was derived from
 }
 
 // ProcessedTransaction represents a processed transaction ready for callback
@@ -150,5 +156,5 @@ type ProcessedTransaction struct {
 	NativeTransfers []NativeTransfer
 	TokenTransfers  []TokenTransfer
 	Fee             int64
-	Timestamp       int64
+	Timestamp       int64 // Block time in Unix seconds, zero when unknown
 }
@@ -0,0 +1,78 @@
+package watch
+
+import "sync"
+
+// Registry keeps the addresses of several sources, such as webhooks, and
+// maintains a Set of their union. An address stays in the set while any
+// source still holds it.
+type Registry struct {
+	mu      sync.Mutex
+	set     *Set
+	sources map[string]map[string]struct{}
+}
+
+// NewRegistry creates a registry maintaining set
+func NewRegistry(set *Set) *Registry {
+	return &Registry{
+		set:     set,
+		sources: make(map[string]map[string]struct{}),
+	}
+}
+
+// Load replaces every source with the given addresses per source and
+// rebuilds the set from them
+func (r *Registry) Load(sources map[string][]string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.sources = make(map[string]map[string]struct{}, len(sources))
+	var all []string
+	for source, addresses := range sources {
+		held := make(map[string]struct{}, len(addresses))
+		for _, addr := range addresses {
+			if key := r.set.normalize(addr); key != "" {
+				held[key] = struct{}{}
+				all = append(all, key)
+			}
+		}
+		r.sources[source] = held
+	}
+	r.set.Replace(all)
+}
+
+// Update adds and removes addresses of one source. Removed addresses leave
+// the set only when no other source holds them.
+func (r *Registry) Update(source string, add, remove []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	held, ok := r.sources[source]
+	if !ok {
+		held = make(map[string]struct{}, len(add))
+		r.sources[source] = held
+	}
+
+	for _, addr := range add {
+		if key := r.set.normalize(addr); key != "" {
+			held[key] = struct{}{}
+			r.set.Add(key)
+		}
+	}
+	for _, addr := range remove {
+		key := r.set.normalize(addr)
+		delete(held, key)
+		if !r.heldByAny(key) {
+			r.set.Remove(key)
+		}
+	}
+}
+
+// heldByAny reports whether any source holds key; the caller must hold the lock
+func (r *Registry) heldByAny(key string) bool {
+	for _, held := range r.sources {
+		if _, ok := held[key]; ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,161 @@
+package watch
+
+import (
+	"strings"
+	"sync"
+)
+
+// Direction describes a transfer relative to the watched addresses
+type Direction string
+
+// Transfer directions
+const (
+	DirectionIncoming  Direction = "incoming"  // Only the recipient is watched
+	DirectionOutgoing  Direction = "outgoing"  // Only the sender is watched
+	DirectionSelf      Direction = "self"      // Sender and recipient are both watched
+	DirectionUnrelated Direction = "unrelated" // Neither side is watched
+)
+
+// AddressSet reports whether an address is watched
+type AddressSet interface {
+	Contains(address string) bool
+}
+
+// Normalizer maps an address to the form used for set membership
+type Normalizer func(address string) string
+
+// LowerCase normalizes hex addresses, which are case-insensitive
+func LowerCase(address string) string {
+	return strings.ToLower(strings.TrimSpace(address))
+}
+
+// Exact keeps addresses as they are, for case-sensitive encodings such as base58
+func Exact(address string) string {
+	return strings.TrimSpace(address)
+}
+
+// Set is a concurrency-safe AddressSet
+type Set struct {
+	mu        sync.RWMutex
+	normalize Normalizer
+	addresses map[string]struct{}
+}
+
+// NewSet creates a set holding addresses. A nil normalize defaults to Exact.
+func NewSet(normalize Normalizer, addresses ...string) *Set {
+	if normalize == nil {
+		normalize = Exact
+	}
+
+	s := &Set{
+		normalize: normalize,
+		addresses: make(map[string]struct{}, len(addresses)),
+	}
+	s.Add(addresses...)
+	return s
+}
+
+// Add adds addresses to the set
+func (s *Set) Add(addresses ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, addr := range addresses {
+		if key := s.normalize(addr); key != "" {
+			s.addresses[key] = struct{}{}
+		}
+	}
+}
+
+// Remove removes addresses from the set
+func (s *Set) Remove(addresses ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, addr := range addresses {
+		delete(s.addresses, s.normalize(addr))
+	}
+}
+
+// Replace replaces the contents of the set
+func (s *Set) Replace(addresses []string) {
+	next := make(map[string]struct{}, len(addresses))
+	for _, addr := range addresses {
+		if key := s.normalize(addr); key != "" {
+			next[key] = struct{}{}
+		}
+	}
+
+	s.mu.Lock()
+	s.addresses = next
+	s.mu.Unlock()
+}
+
+// Contains reports whether address is in the set
+func (s *Set) Contains(address string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, ok := s.addresses[s.normalize(address)]
+	return ok
+}
+
+// Addresses returns the normalized addresses in the set, in no particular order
+func (s *Set) Addresses() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	addresses := make([]string, 0, len(s.addresses))
+	for addr := range s.addresses {
+		addresses = append(addresses, addr)
+	}
+	return addresses
+}
+
+// Len returns the number of addresses in the set
+func (s *Set) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.addresses)
+}
+
+// Classify returns the direction of a transfer and the watched address it
+// matched. For self transfers the recipient is returned.
+func Classify(set AddressSet, from, to string) (Direction, string) {
+	fromWatched := from != "" && set.Contains(from)
+	toWatched := to != "" && set.Contains(to)
+
+	switch {
+	case fromWatched && toWatched:
+		return DirectionSelf, to
+	case toWatched:
+		return DirectionIncoming, to
+	case fromWatched:
+		return DirectionOutgoing, from
+	default:
+		return DirectionUnrelated, ""
+	}
+}
+
+// Filter is a set of directions to deliver
+type Filter map[Direction]struct{}
+
+// NewFilter creates a filter that allows the given directions.
+// An empty filter allows every direction.
+func NewFilter(directions ...Direction) Filter {
+	f := make(Filter, len(directions))
+	for _, d := range directions {
+		f[d] = struct{}{}
+	}
+	return f
+}
+
+// Allows reports whether d passes the filter
+func (f Filter) Allows(d Direction) bool {
+	if len(f) == 0 {
+		return true
+	}
+	_, ok := f[d]
+	return ok
+}
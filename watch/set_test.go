@@ -0,0 +1,119 @@
+package watch
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSet(t *testing.T) {
+	set := NewSet(LowerCase, "0xABC", " 0xdef ", "")
+	if set.Len() != 2 {
+		t.Fatalf("Len() = %d, want 2", set.Len())
+	}
+	for _, addr := range []string{"0xabc", "0xABC", "0xDEF"} {
+		if !set.Contains(addr) {
+			t.Errorf("Contains(%q) = false, want true", addr)
+		}
+	}
+
+	set.Add("0x123")
+	set.Remove("0xAbC")
+	got := set.Addresses()
+	slices.Sort(got)
+	if want := []string{"0x123", "0xdef"}; !slices.Equal(got, want) {
+		t.Errorf("Addresses() = %v, want %v", got, want)
+	}
+
+	set.Replace([]string{"0x999"})
+	if set.Contains("0xdef") || !set.Contains("0x999") || set.Len() != 1 {
+		t.Errorf("Replace left %v, want only 0x999", set.Addresses())
+	}
+
+	exact := NewSet(nil, "So1ana")
+	if exact.Contains("so1ana") || !exact.Contains("So1ana") {
+		t.Error("a set without a normalizer should match addresses exactly")
+	}
+}
+
+func TestClassify(t *testing.T) {
+	set := NewSet(LowerCase, "0xaaa", "0xbbb")
+
+	tests := []struct {
+		name          string
+		from, to      string
+		wantDirection Direction
+		wantAddress   string
+	}{
+		{"incoming", "0xccc", "0xAAA", DirectionIncoming, "0xAAA"},
+		{"outgoing", "0xaaa", "0xccc", DirectionOutgoing, "0xaaa"},
+		{"self", "0xaaa", "0xbbb", DirectionSelf, "0xbbb"},
+		{"unrelated", "0xccc", "0xddd", DirectionUnrelated, ""},
+		{"contract creation", "0xaaa", "", DirectionOutgoing, "0xaaa"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			direction, address := Classify(set, tt.from, tt.to)
+			if direction != tt.wantDirection || address != tt.wantAddress {
+				t.Errorf("Classify(%q, %q) = %s, %q; want %s, %q", tt.from, tt.to, direction, address, tt.wantDirection, tt.wantAddress)
+			}
+		})
+	}
+}
+
+func TestFilter(t *testing.T) {
+	all := []Direction{DirectionIncoming, DirectionOutgoing, DirectionSelf, DirectionUnrelated}
+
+	tests := []struct {
+		name    string
+		filter  Filter
+		allowed []Direction
+	}{
+		{"empty allows all", NewFilter(), all},
+		{"nil allows all", nil, all},
+		{"deposits", NewFilter(DirectionIncoming, DirectionSelf), []Direction{DirectionIncoming, DirectionSelf}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, direction := range all {
+				if got, want := tt.filter.Allows(direction), slices.Contains(tt.allowed, direction); got != want {
+					t.Errorf("Allows(%s) = %v, want %v", direction, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRegistryKeepsSharedAddresses(t *testing.T) {
+	set := NewSet(LowerCase)
+	registry := NewRegistry(set)
+	registry.Load(map[string][]string{
+		"wh-1": {"0xAAA", "0xbbb"},
+		"wh-2": {"0xaaa"},
+	})
+	if set.Len() != 2 {
+		t.Fatalf("loaded set has %d addresses, want 2", set.Len())
+	}
+
+	registry.Update("wh-1", []string{"0xccc"}, []string{"0xaaa", "0xbbb"})
+	if !set.Contains("0xaaa") {
+		t.Error("address still held by wh-2 was removed")
+	}
+	if set.Contains("0xbbb") {
+		t.Error("address no webhook holds is still watched")
+	}
+	if !set.Contains("0xccc") {
+		t.Error("added address is not watched")
+	}
+
+	registry.Update("wh-2", nil, []string{"0xAAA"})
+	if set.Contains("0xaaa") {
+		t.Error("address removed from its last webhook is still watched")
+	}
+
+	registry.Load(map[string][]string{"wh-3": {"0xddd"}})
+	if got := set.Addresses(); !slices.Equal(got, []string{"0xddd"}) {
+		t.Errorf("reloaded set = %v, want [0xddd]", got)
+	}
+}
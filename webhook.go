@@ -10,6 +10,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/dawitel/alchemy-webhook/watch"
 	"github.com/rs/zerolog"
 	"github.com/sony/gobreaker"
 )
@@ -30,6 +31,7 @@ type WebhookManager struct {
 	mu             sync.RWMutex
 	webhooks       map[string]*WebhookInfo
 	network        string
+	normalize      watch.Normalizer
 }
 
 // NewWebhookManager creates a new webhook manager
@@ -62,9 +64,37 @@ func NewWebhookManager(cfg *Config, logger zerolog.Logger, network string) *Webh
 		circuitBreaker: circuitBreaker,
 		webhooks:       make(map[string]*WebhookInfo),
 		network:        network,
+		normalize:      watch.Exact,
 	}
 }
 
+// SetAddressNormalizer sets how addresses are formatted before they are sent
+// to or returned from Alchemy. The default keeps them as they are.
+func (wm *WebhookManager) SetAddressNormalizer(normalize watch.Normalizer) {
+	if normalize == nil {
+		normalize = watch.Exact
+	}
+	wm.normalize = normalize
+}
+
+// normalizeAddresses formats addresses and drops empty values and duplicates
+func (wm *WebhookManager) normalizeAddresses(addresses []string) []string {
+	seen := make(map[string]struct{}, len(addresses))
+	normalized := make([]string, 0, len(addresses))
+	for _, addr := range addresses {
+		addr = wm.normalize(addr)
+		if addr == "" {
+			continue
+		}
+		if _, ok := seen[addr]; ok {
+			continue
+		}
+		seen[addr] = struct{}{}
+		normalized = append(normalized, addr)
+	}
+	return normalized
+}
+
 func (wm *WebhookManager) getAuthToken() string {
 	authToken := wm.cfg.AlchemyAuthToken
 	if authToken == "" || authToken == "YOUR_ALCHEMY_AUTH_TOKEN_HERE" {
@@ -256,11 +286,14 @@ func (wm *WebhookManager) GetWebhookAddresses(ctx context.Context, webhookID str
 		return err
 	})
 
-	return allAddresses, err
+	return wm.normalizeAddresses(allAddresses), err
 }
 
 // UpdateWebhookAddresses updates addresses for a webhook
 func (wm *WebhookManager) UpdateWebhookAddresses(ctx context.Context, webhookID string, addressesToAdd, addressesToRemove []string) error {
+	addressesToAdd = wm.normalizeAddresses(addressesToAdd)
+	addressesToRemove = wm.normalizeAddresses(addressesToRemove)
+
 	return wm.executeWithRetry(ctx, fmt.Sprintf("update_webhook_%s", webhookID), func() error {
 		_, err := wm.circuitBreaker.Execute(func() (interface{}, error) {
 			reqBody := map[string]interface{}{